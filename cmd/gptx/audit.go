@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/audit"
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/urfave/cli/v3"
+)
+
+func auditCMD(config *cfg.Config) *cli.Command {
+	var since, until, project string
+	var filter audit.Filter
+	var limit int
+	var asJSON bool
+
+	return &cli.Command{
+		Name: "audit", Usage: "Show the audit log of tool calls",
+		Description: AUDIT_DESC,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "since", Usage: "Show records since a date or a duration ago (2006-01-02, 24h, 7d)",
+				Destination: &since,
+			},
+			&cli.StringFlag{
+				Name: "until", Usage: "Show records before a date or a duration ago",
+				Destination: &until,
+			},
+			&cli.StringFlag{
+				Name: "tool", Usage: "Show calls of a tool",
+				Destination: &filter.Tool,
+			},
+			&cli.StringFlag{
+				Name: "project", Usage: "Show records of the project containing a directory",
+				Destination: &project, TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name: "type", Usage: "Show records of a type (tool, request, response, all)",
+				Destination: &filter.Type, Value: audit.ToolRecord,
+			},
+			&cli.IntFlag{
+				Name: "limit", Usage: "Show the latest records only (0 for all)",
+				Destination: &limit, Value: 50,
+			},
+			&cli.BoolFlag{
+				Name: "json", Usage: "Print records as JSON lines",
+				Destination: &asJSON,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cfg.AppDir == "" {
+				return fmt.Errorf("audit: no app directory")
+			}
+
+			var err error
+			if filter.Since, err = parseTime(since); err != nil {
+				return fmt.Errorf("since: %w", err)
+			}
+			if filter.Until, err = parseTime(until); err != nil {
+				return fmt.Errorf("until: %w", err)
+			}
+			if filter.Type == "all" {
+				filter.Type = ""
+			}
+			if project != "" {
+				filter.Project = projectRoot(ctx, project)
+			}
+
+			log, err := auditLog(*config) // Queries don't redact
+			if err != nil {
+				return err
+			}
+			records, err := log.Query(filter)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(records) > limit {
+				records = records[len(records)-limit:]
+			}
+
+			for _, record := range records {
+				if asJSON {
+					data, err := json.Marshal(record)
+					if err != nil {
+						return fmt.Errorf("audit: %w", err)
+					}
+					Print(string(data) + "\n")
+					continue
+				}
+				printRecord(record)
+			}
+			return nil
+		},
+	}
+}
+
+// auditLog returns the audit log in the app directory. API keys and scrub
+// patterns are redacted from its records.
+func auditLog(config cfg.Config, keys ...string) (*audit.Log, error) {
+	dir := filepath.Join(cfg.AppDir, "audit")
+	size := int64(config.AuditSize) * 1024 * 1024
+	return audit.NewLog(dir, size, config.AuditKeep, keys, config.Scrub)
+}
+
+// printRecord prints a record of the audit log as a line.
+func printRecord(record audit.Record) {
+	stamp := record.Time.Local().Format(time.DateTime)
+	switch record.Type {
+	case audit.ToolRecord:
+		decision := G + record.Decision + Reset
+		if record.Decision != audit.Allowed {
+			decision = Y + record.Decision + Reset
+		}
+		status := "-"
+		if record.Exit != nil {
+			status = "exit " + strconv.Itoa(*record.Exit)
+		}
+		Print("%s%s%s  %s  %s  %s  %dms  %s  %s\n", Dim, stamp, Reset,
+			record.Tool, decision, status, record.Duration, record.Project, record.Args)
+		if record.Requested != "" {
+			Print("    %srequested: %s%s\n", Dim, record.Requested, Reset)
+		}
+	default:
+		messages := fmt.Sprintf("%d messages", len(record.Messages))
+		if len(record.Messages) == 1 {
+			messages = "1 message"
+		}
+		Print("%s%s%s  %s  %s  %s  %s\n", Dim, stamp, Reset,
+			record.Type, record.Model, messages, record.Project)
+	}
+	if record.Error != "" {
+		Print("    %s%s%s\n", R, record.Error, Reset)
+	}
+}
+
+// parseTime parses a date, a date and time, or a duration before now,
+// which can be in days. Empty values are the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected a date (2006-01-02) or a duration (24h, 7d), got %q", value)
+}
+
+// projectRoot returns the root of the repository containing a directory,
+// or the directory outside a repository.
+func projectRoot(ctx context.Context, dir string) string {
+	if root, err := git.Run(ctx, "-C", dir, "rev-parse", "--show-toplevel"); err == nil {
+		return strings.TrimSpace(root)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/internal/audit"
+)
+
+// TestAudit records tool calls only with --audit, and queries them with
+// the gptx audit filters.
+func TestAudit(t *testing.T) {
+	script, err := filepath.Abs("testdata/tool.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	msg := []string{"msg", "--provider", "fake", "--script", script, "--shell", "sh", "greet", "me"}
+
+	runCLIIn(t, dir, msg...)
+	if _, err := os.Stat(filepath.Join(dir, "gptx", "audit")); !os.IsNotExist(err) {
+		t.Fatalf("audit log written without --audit: %v", err)
+	}
+	runCLIIn(t, dir, append([]string{"--audit"}, msg...)...)
+
+	query := func(args ...string) []audit.Record {
+		t.Helper()
+		stdout, _ := runCLIIn(t, dir, append([]string{"audit", "--json"}, args...)...)
+		var records []audit.Record
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			if line == "" {
+				continue
+			}
+			var record audit.Record
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("audit line %q: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+
+	records := query()
+	if len(records) != 1 || records[0].Tool != "shell" || records[0].Args != `{"cmd": "echo hello"}` ||
+		records[0].Decision != audit.Allowed || records[0].Exit == nil || *records[0].Exit != 0 {
+		t.Fatalf("records = %+v, want the shell call", records)
+	}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--tool", "shell"}, 1},
+		{[]string{"--tool", "read"}, 0},
+		{[]string{"--since", "1h"}, 1},
+		{[]string{"--since", "2d", "--until", "1d"}, 0},
+		{[]string{"--project", dir}, 1},
+		{[]string{"--project", t.TempDir()}, 0},
+		{[]string{"--type", "request"}, 0},
+		{[]string{"--type", "all"}, 1},
+	}
+	for _, test := range tests {
+		if got := query(test.args...); len(got) != test.want {
+			t.Errorf("gptx audit %s = %d records, want %d", strings.Join(test.args, " "), len(got), test.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/internal/auth"
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// authCMD creates the auth command for managing API keys in the keyring.
+func authCMD(config *cfg.Config) *cli.Command {
+	return &cli.Command{
+		Name: "auth", Usage: "Manage provider API keys",
+		Description: AUTH_DESC,
+		Commands: []*cli.Command{
+			{
+				Name: "login", Usage: "Store an API key in the OS keyring",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					account, err := keyAccount(*config)
+					if err != nil {
+						return fmt.Errorf("login: %w", err)
+					}
+					keyring, err := auth.DefaultKeyring()
+					if err != nil {
+						return fmt.Errorf("login: %w", err)
+					}
+
+					key, err := readKey(account)
+					if err != nil {
+						return fmt.Errorf("login: %w", err)
+					}
+					if err := keyring.Set(account, key); err != nil {
+						return fmt.Errorf("login: %w", err)
+					}
+					Info("Stored %s API key in the keyring", account)
+					return nil
+				},
+			},
+			{
+				Name: "logout", Usage: "Remove an API key from the OS keyring",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					account, err := keyAccount(*config)
+					if err != nil {
+						return fmt.Errorf("logout: %w", err)
+					}
+					keyring, err := auth.DefaultKeyring()
+					if err != nil {
+						return fmt.Errorf("logout: %w", err)
+					}
+
+					err = keyring.Delete(account)
+					if errors.Is(err, auth.ErrNotFound) {
+						Warn("No %s API key in the keyring", account)
+						return nil
+					} else if err != nil {
+						return fmt.Errorf("logout: %w", err)
+					}
+					Info("Removed %s API key from the keyring", account)
+					return nil
+				},
+			},
+			{
+				Name: "status", Usage: "Show where the API key comes from",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					key, source, err := auth.Lookup(
+						ctx, config.Provider, config.BaseURL, config.APIKey, config.KeyCmd,
+					)
+					if err != nil {
+						return fmt.Errorf("status: %w", err)
+					}
+
+					// Keys from the config have finer sources
+					if source == auth.SourceConfig {
+						if s, err := lookupSetting(cmd, "key"); err == nil {
+							source = s.source()
+						}
+					}
+					account := auth.Account(config.Provider, config.BaseURL)
+					Print("%s: %s %s(%s)%s\n", account, redact(key), Dim, source, Reset)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// keyAccount returns the keyring account of the configured provider's key.
+func keyAccount(config cfg.Config) (string, error) {
+	if _, ok := auth.Providers[config.Provider]; !ok {
+		return "", fmt.Errorf("provider %s does not use an API key", config.Provider)
+	}
+	return auth.Account(config.Provider, config.BaseURL), nil
+}
+
+// readKey reads an API key from the terminal without echo, or from stdin.
+func readKey(account string) (string, error) {
+	var key string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		PrintErr("%s API key: ", account)
+		data, err := term.ReadPassword(fd)
+		PrintErr("\n")
+		if err != nil {
+			return "", err
+		}
+		key = string(data)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading key: %w", err)
+		}
+		key = line
+	}
+
+	if key = strings.TrimSpace(key); key == "" {
+		return "", fmt.Errorf("empty API key")
+	}
+	return key, nil
+}
@@ -4,7 +4,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/mohdfareed/gptx-cli/internal/cfg"
 	"github.com/urfave/cli/v3"
@@ -16,6 +16,8 @@ func mainCMD() *cli.Command {
 		Name: cfg.AppName, Usage: "Interact with an LLM models",
 		Description:    APP_DESC,
 		DefaultCommand: "msg",
+
+		EnableShellCompletion: true,
 	}
 }
 
@@ -47,35 +49,104 @@ func msgCMD(config *cfg.Config) *cli.Command {
 	}
 }
 
-func configCMD() *cli.Command {
+func filesCMD(config *cfg.Config) *cli.Command {
+	var maxAge time.Duration
+	var all bool
 	return &cli.Command{
-		Name: "cfg", Usage: "Show current configuration",
-		Description: CONFIG_DESC,
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			// Convert config to a map for display
-			configMap := cfg.EnvMap()
+		Name: "files", Usage: "Manage uploaded attachments",
+		Description: FILES_DESC,
+		Commands: []*cli.Command{
+			{
+				Name: "list", Usage: "List cached uploads",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					client, err := createClient(ctx, *config)
+					if err != nil {
+						return err
+					}
 
-			// Print each key-value pair
-			for _, key := range configMap {
-				keyName := Bold + Dim + key + Reset
-				value := configMap[key]
+					for _, upload := range client.Uploads() {
+						used := upload.Used.Format(time.DateTime)
+						Print("%s %s %s(%d bytes, used %s)%s\n",
+							upload.ID, upload.Name, Dim, upload.Size, used, Reset)
+					}
+					return nil
+				},
+			},
+			{
+				Name: "prune", Usage: "Delete stale uploads",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name: "age", Usage: "Prune uploads unused for this long",
+						Value: 30 * 24 * time.Hour, Destination: &maxAge,
+					},
+					&cli.BoolFlag{
+						Name: "all", Usage: "Prune all uploads",
+						Destination: &all,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					client, err := createClient(ctx, *config)
+					if err != nil {
+						return err
+					}
 
-				// Multiline values need quotes (escape existing quotes)
-				if strings.Contains(value, "\n") {
-					quote := Y + "\"" + Reset
-					str := strings.ReplaceAll(value, "\"", "\\\"")
-					value = quote + str + quote
-				}
+					if all {
+						maxAge = 0
+					}
+					pruned, err := client.PruneUploads(ctx, maxAge)
+					if err != nil {
+						return fmt.Errorf("prune: %w", err)
+					}
+					Info("Pruned %d uploads", pruned)
+					return nil
+				},
+			},
+		},
+	}
+}
 
-				Print(keyName + M + "=" + Reset + value + "\n")
-			}
+// cacheCMD creates the cache command for managing cached responses.
+func cacheCMD(config *cfg.Config) *cli.Command {
+	return &cli.Command{
+		Name: "cache", Usage: "Manage cached responses",
+		Description: CACHE_DESC,
+		Commands: []*cli.Command{
+			{
+				Name: "stats", Usage: "Show cache statistics",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cfg.AppDir == "" {
+						return fmt.Errorf("cache: no app directory")
+					}
+					stats, err := responseCache(*config).Stats()
+					if err != nil {
+						return err
+					}
 
-			// Show source files
-			PrintErr(Dim + Bold + "Config Files:\n" + Reset)
-			for _, file := range cfg.ConfigFiles() {
-				PrintErr("- %s\n", file)
-			}
-			return nil
+					Print("%sDirectory:%s %s\n", Bold, Reset, stats.Dir)
+					Print("%sEntries:%s   %d (%d expired)\n", Bold, Reset, stats.Entries, stats.Expired)
+					Print("%sSize:%s      %.1f of %d MB\n", Bold, Reset,
+						float64(stats.Size)/1024/1024, config.CacheSize)
+					Print("%sHits:%s      %d\n", Bold, Reset, stats.Hits)
+					if !stats.Oldest.IsZero() {
+						Print("%sOldest:%s    %s\n", Bold, Reset, stats.Oldest.Format(time.DateTime))
+					}
+					return nil
+				},
+			},
+			{
+				Name: "clear", Usage: "Delete all cached responses",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cfg.AppDir == "" {
+						return fmt.Errorf("cache: no app directory")
+					}
+					cleared, err := responseCache(*config).Clear()
+					if err != nil {
+						return fmt.Errorf("clear: %w", err)
+					}
+					Info("Cleared %d cached responses", cleared)
+					return nil
+				},
+			},
 		},
 	}
 }
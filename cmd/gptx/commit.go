@@ -0,0 +1,194 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/urfave/cli/v3"
+)
+
+// COMMIT_PROMPT is the system prompt template for commit messages.
+// It is filled with the maximum subject length and the style guide.
+const COMMIT_PROMPT string = `
+You write git commit messages for staged changes.
+Reply with the commit message only, without code fences or commentary.
+
+Format:
+- A subject line of at most %d characters, in the imperative mood.
+- A blank line, then a short body explaining what changed and why,
+  wrapped at 72 characters. Omit the body for trivial changes.
+- %s
+- Match the tone of the recent commits when they are provided.
+`
+
+// commitStyles maps commit message styles to their style guides.
+var commitStyles = map[string]string{
+	"conventional": "Follow Conventional Commits: `type(scope): subject`, " +
+		"with types such as feat, fix, docs, refactor, test and chore.",
+	"gitmoji": "Start the subject with a single gitmoji that fits the " +
+		"change (e.g. ✨ feature, 🐛 fix, 📝 docs, ♻️ refactor).",
+	"plain": "Use a plain, capitalized subject without prefixes.",
+}
+
+// MARK: Command
+// ============================================================================
+
+// commitCMD creates the commit command for generating commit messages.
+func commitCMD(config *cfg.Config) *cli.Command {
+	var style string
+	var subject int
+	var edit, apply bool
+
+	return &cli.Command{
+		Name: "commit", Usage: "Write a commit message for staged changes",
+		Description: COMMIT_DESC,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "style", Usage: "Message style (conventional, gitmoji, plain)",
+				Sources: cli.EnvVars(cfg.EnvVarPrefix + "COMMIT_STYLE"),
+				Value:   "conventional", Destination: &style,
+				Validator: func(style string) error {
+					if _, ok := commitStyles[style]; !ok {
+						return fmt.Errorf("unknown commit style: %s", style)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name: "subject", Usage: "Maximum subject line length",
+				Sources: cli.EnvVars(cfg.EnvVarPrefix + "COMMIT_SUBJECT"),
+				Value:   72, Destination: &subject,
+			},
+			&cli.BoolFlag{
+				Name: "edit", Usage: "Review the message in the editor",
+				Destination: &edit,
+			},
+			&cli.BoolFlag{
+				Name: "apply", Usage: "Commit the staged changes with the message",
+				Destination: &apply,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			msg, err := commitMessage(ctx, *config, style, subject)
+			if err != nil {
+				return fmt.Errorf("commit: %w", err)
+			}
+
+			// Review the message in the editor
+			if edit {
+				if editor == "" {
+					return fmt.Errorf("commit: no editor set, use --editor")
+				}
+				if msg, err = editorPrompt(editor, msg); err != nil {
+					return fmt.Errorf("commit: %w", err)
+				}
+				if msg == "" {
+					return fmt.Errorf("commit: empty commit message")
+				}
+			}
+
+			if !apply {
+				Print(msg + "\n")
+				return nil
+			}
+			return gitCommit(ctx, msg)
+		},
+	}
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// commitMessage asks the model for a commit message for the staged changes.
+func commitMessage(
+	ctx context.Context, config cfg.Config, style string, subject int,
+) (string, error) {
+	staged, err := git.StagedDiff().Context(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(staged) == 0 {
+		return "", fmt.Errorf("no staged changes")
+	}
+
+	// Only the diff and the recent history are relevant
+	config.SysPrompt = fmt.Sprintf(COMMIT_PROMPT, subject, commitStyles[style])
+	config.Files, config.Shell, config.WebSearch = nil, "", false
+	config = withoutContext(config)
+
+	callbacks := events.NewCallbacks().
+		WithErrorHandler(func(err error) {
+			Debug("Model error: %s", err)
+		}).
+		WithRetryHandler(func(err error, delay time.Duration) {
+			Info("Retrying in %s: %s", delay.Round(time.Millisecond), err)
+		}).
+		WithDoneHandler(func(usage string) {
+			Debug("Usage: %s", usage)
+		}).
+		Build()
+
+	model, err := createModel(ctx, config, callbacks, gptx.WithHandler(status))
+	if err != nil {
+		return "", err
+	}
+	model.AttachContext(gptx.Attachments(staged))
+	if _, err := git.Run(ctx, "rev-parse", "--verify", "HEAD"); err == nil {
+		model.AttachContext(git.Log(10)) // match the style of recent commits
+	}
+	if err := model.Message(ctx, "Write the commit message."); err != nil {
+		return "", fmt.Errorf("model error: %w", err)
+	}
+
+	msg := cleanCommitMessage(lastReply(model.History()))
+	if msg == "" {
+		return "", fmt.Errorf("model returned an empty message")
+	}
+	if line, _, _ := strings.Cut(msg, "\n"); len([]rune(line)) > subject {
+		Warn("subject is longer than %d characters", subject)
+	}
+	return msg, nil
+}
+
+// lastReply returns the text of the model's last reply in a conversation.
+func lastReply(history []gptx.Message) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "assistant" {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+// cleanCommitMessage strips code fences and surrounding whitespace.
+func cleanCommitMessage(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if strings.HasPrefix(msg, "```") && strings.HasSuffix(msg, "```") {
+		msg = strings.TrimSuffix(msg, "```")
+		if _, body, ok := strings.Cut(msg, "\n"); ok {
+			msg = body
+		}
+	}
+	return strings.TrimSpace(msg)
+}
+
+// gitCommit commits the staged changes with the given message.
+func gitCommit(ctx context.Context, msg string) error {
+	cmd := exec.CommandContext(ctx, "git", "commit", "-F", "-")
+	cmd.Stdin = strings.NewReader(msg + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
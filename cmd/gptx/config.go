@@ -0,0 +1,272 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+	"github.com/urfave/cli/v3"
+)
+
+// setting is a configuration flag and its config file key.
+type setting struct {
+	flag cli.Flag
+	key  cfg.Key
+}
+
+// MARK: Command
+// ============================================================================
+
+// configCMD creates the config command for showing and editing settings.
+func configCMD(config *cfg.Config) *cli.Command {
+	var global, local, reveal, showPrompt bool
+	scopeFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name: "global", Usage: "Edit the global config file",
+			Destination: &global,
+		},
+		&cli.BoolFlag{
+			Name: "local", Usage: "Edit the current directory's config file (default)",
+			Destination: &local,
+		},
+	}
+
+	// configFile returns the config file selected by the scope flags
+	configFile := func() (string, error) {
+		if global && local {
+			return "", fmt.Errorf("--global and --local are mutually exclusive")
+		} else if global {
+			return cfg.GlobalConfigFile()
+		}
+		return cfg.LocalConfigFile()
+	}
+
+	return &cli.Command{
+		Name: "cfg", Usage: "Show and edit configuration",
+		Description: CONFIG_DESC,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name: "show-prompt", Usage: "Print the assembled system prompt",
+				Destination: &showPrompt,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if showPrompt {
+				registry := tools.NewRegistry()
+				setupTools(*config, registry)
+				prompt, err := systemPrompt(*config, registry)
+				if err != nil {
+					return err
+				}
+				Print("%s\n", prompt)
+				return nil
+			}
+
+			for _, s := range settings(cmd) {
+				value, source := s.value(cmd), s.source()
+				if s.secret() {
+					value = redact(value)
+				}
+
+				// Multiline values need quotes (escape existing quotes)
+				if strings.Contains(value, "\n") {
+					quote := Y + "\"" + Reset
+					str := strings.ReplaceAll(value, "\"", "\\\"")
+					value = quote + str + quote
+				}
+
+				keyName := Bold + Dim + s.key.Env + Reset
+				Print(keyName + M + "=" + Reset + value + Dim + " # " + source + Reset + "\n")
+			}
+
+			// Show source files
+			if cfg.Profile != "" {
+				PrintErr(Dim+Bold+"Profile: "+Reset+"%s\n", cfg.Profile)
+			}
+			PrintErr(Dim + Bold + "Config Files:\n" + Reset)
+			for _, file := range cfg.ConfigFiles() {
+				PrintErr("- %s\n", file)
+			}
+			return nil
+		},
+		Commands: []*cli.Command{
+			{
+				Name: "get", Usage: "Print a setting's effective value",
+				ArgsUsage: "<key>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name: "reveal", Usage: "Print secrets instead of redacting them",
+						Destination: &reveal,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					s, err := lookupSetting(cmd, cmd.Args().First())
+					if err != nil {
+						return err
+					}
+
+					value := s.value(cmd)
+					if s.secret() && !reveal {
+						value = redact(value)
+					}
+					Print(value + "\n")
+					Debug("Source: %s", s.source())
+					return nil
+				},
+			},
+			{
+				Name: "set", Usage: "Set a setting in a config file",
+				ArgsUsage: "<key> <value>",
+				Flags:     scopeFlags,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() != 2 {
+						return fmt.Errorf("set: expected a key and a value")
+					}
+					s, err := lookupSetting(cmd, cmd.Args().Get(0))
+					if err != nil {
+						return err
+					}
+					path, err := configFile()
+					if err != nil {
+						return err
+					}
+					if s.key.GlobalOnly() && !global {
+						return fmt.Errorf("set: %s can only be set in the global config (--global)", s.key.Env)
+					}
+
+					if err := cfg.SetValue(path, s.key, cmd.Args().Get(1)); err != nil {
+						return fmt.Errorf("set: %w", err)
+					}
+					Info("Set %s in %s", s.key.Env, path)
+					if cfg.Sources[s.key.Env] == "env" {
+						Warn("%s is overridden by the environment", s.key.Env)
+					}
+					return nil
+				},
+			},
+			{
+				Name: "unset", Usage: "Remove a setting from a config file",
+				ArgsUsage: "<key>",
+				Flags:     scopeFlags,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					s, err := lookupSetting(cmd, cmd.Args().First())
+					if err != nil {
+						return err
+					}
+					path, err := configFile()
+					if err != nil {
+						return err
+					}
+
+					removed, err := cfg.UnsetValue(path, s.key)
+					if err != nil {
+						return fmt.Errorf("unset: %w", err)
+					} else if !removed {
+						Warn("%s is not set in %s", s.key.Env, path)
+						return nil
+					}
+					Info("Removed %s from %s", s.key.Env, path)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// MARK: Settings
+// ============================================================================
+
+// settings returns the app's configuration settings, sorted by key.
+// Flags with several environment variables are listed under the first.
+func settings(cmd *cli.Command) []setting {
+	var result []setting
+	for _, flag := range cmd.Root().Flags {
+		if keys := cfg.FlagKeys([]cli.Flag{flag}); len(keys) > 0 {
+			result = append(result, setting{flag, keys[0]})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b setting) int {
+		return strings.Compare(a.key.Env, b.key.Env)
+	})
+	return result
+}
+
+// lookupSetting finds a setting by environment variable, config file key,
+// or flag name.
+func lookupSetting(cmd *cli.Command, name string) (setting, error) {
+	if name == "" {
+		return setting{}, fmt.Errorf("missing setting key")
+	}
+	for _, s := range settings(cmd) {
+		names := append([]string{s.key.Env, s.key.Name}, s.flag.Names()...)
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return s, nil
+			}
+		}
+	}
+	return setting{}, fmt.Errorf("unknown setting: %s", name)
+}
+
+// value returns the setting's effective value, joining lists with the
+// key's separator so that it can be set again.
+func (s setting) value(cmd *cli.Command) string {
+	switch value := cmd.Value(s.flag.Names()[0]).(type) {
+	case []string:
+		return strings.Join(value, s.key.Separator())
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// source returns where the setting's value came from: "flag", "env",
+// a config file, or "default".
+func (s setting) source() string {
+	if flagArg(s.flag.Names(), os.Args[1:]) {
+		return "flag"
+	}
+	for _, key := range cfg.FlagKeys([]cli.Flag{s.flag}) {
+		if source, ok := cfg.Sources[key.Env]; ok {
+			return source
+		}
+	}
+	return "default"
+}
+
+// secret reports whether the setting's value must not be displayed.
+func (s setting) secret() bool {
+	return s.key.Env == cfg.EnvVarPrefix+"API_KEY"
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// flagArg reports whether a flag with one of the names is in args.
+func flagArg(names []string, args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && slices.Contains(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact hides all but the last characters of a secret.
+func redact(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
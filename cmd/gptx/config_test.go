@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+// TestConfigRoundTrip reads list settings back with their own separators,
+// so that values printed by cfg get can be set again with cfg set.
+func TestConfigRoundTrip(t *testing.T) {
+	tests := []struct {
+		key, value string
+		global     bool
+	}{
+		{"files", "main.go,docs/*.md", false},
+		{"hooks", "pre_tool=echo a,b\npost_tool=true", true},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		args := []string{"cfg", "set", test.key, test.value}
+		if test.global {
+			args = append(args, "--global")
+		}
+		runCLIIn(t, dir, args...)
+
+		got, _ := runCLIIn(t, dir, "cfg", "get", test.key)
+		if got != test.value+"\n" {
+			t.Errorf("cfg get %s = %q, want %q", test.key, got, test.value+"\n")
+		}
+	}
+}
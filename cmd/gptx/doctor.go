@@ -0,0 +1,182 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/auth"
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultBaseURL is the API used when no base URL is configured.
+const defaultBaseURL = "https://api.openai.com/v1"
+
+// Diagnosis statuses, from best to worst.
+const (
+	statusOK   = "ok"
+	statusWarn = "warn"
+	statusFail = "fail"
+)
+
+// diagnosis is the outcome of a doctor check.
+type diagnosis struct {
+	check  string // What was checked
+	status string // ok, warn or fail
+	detail string // Outcome description
+}
+
+// MARK: Command
+// ============================================================================
+
+// doctorCMD creates the doctor command for diagnosing the setup.
+func doctorCMD(config *cfg.Config) *cli.Command {
+	return &cli.Command{
+		Name: "doctor", Usage: "Diagnose configuration and connectivity",
+		Description: DOCTOR_DESC,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			keys := cfg.FlagKeys(allFlags(cmd.Root()))
+			var results []diagnosis
+			results = append(results, checkConfigFiles(*config, keys)...)
+			results = append(results, checkConfig(*config))
+			key, keyResult := checkKey(ctx, *config)
+			results = append(results, keyResult)
+			results = append(results, checkConnection(ctx, *config, key))
+			results = append(results, checkTools(*config)...)
+
+			colors := map[string]string{statusOK: G, statusWarn: Y, statusFail: R}
+			failed := 0
+			for _, d := range results {
+				status := Bold + colors[d.status] + fmt.Sprintf("%-4s", d.status) + Reset
+				Print("%s %s: %s\n", status, d.check, d.detail)
+				if d.status == statusFail {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("doctor: %d of %d checks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
+
+// MARK: Checks
+// ============================================================================
+
+// checkConfigFiles parses each config file and the selected profile.
+func checkConfigFiles(config cfg.Config, keys []cfg.Key) []diagnosis {
+	var results []diagnosis
+	for _, path := range cfg.ConfigFiles() {
+		warnings, err := cfg.CheckConfigFile(path, keys)
+		switch {
+		case err != nil:
+			results = append(results, diagnosis{"config", statusFail, err.Error()})
+		case len(warnings) > 0:
+			results = append(results, diagnosis{"config", statusWarn, errors.Join(warnings...).Error()})
+		default:
+			results = append(results, diagnosis{"config", statusOK, path})
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, diagnosis{"config", statusOK, "no config files"})
+	}
+
+	if config.Profile != "" {
+		if err := cfg.CheckProfile(config.Profile, keys); err != nil {
+			results = append(results, diagnosis{"profile", statusFail, err.Error()})
+		} else {
+			results = append(results, diagnosis{"profile", statusOK, config.Profile})
+		}
+	}
+	return results
+}
+
+// checkConfig validates the settings against the model's capabilities.
+func checkConfig(config cfg.Config) diagnosis {
+	if err := gptx.ValidateConfig(config); err != nil {
+		detail := strings.ReplaceAll(err.Error(), "\n", "; ")
+		return diagnosis{"settings", statusFail, detail}
+	}
+	if _, ok := gptx.ModelCapabilities(config.Model); !ok {
+		detail := "no capability data for model " + config.Model
+		return diagnosis{"settings", statusWarn, detail}
+	}
+	return diagnosis{"settings", statusOK, "valid for model " + config.Model}
+}
+
+// checkKey resolves the provider's API key.
+func checkKey(ctx context.Context, config cfg.Config) (string, diagnosis) {
+	key, source, err := auth.Lookup(ctx, config.Provider, config.BaseURL, config.APIKey, config.KeyCmd)
+	if err != nil {
+		return "", diagnosis{"api key", statusFail, err.Error()}
+	}
+	return key, diagnosis{"api key", statusOK, config.Provider + " key from " + source}
+}
+
+// checkConnection lists the API's models with the key, if any.
+func checkConnection(ctx context.Context, config cfg.Config, key string) diagnosis {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/models"
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return diagnosis{"connection", statusFail, err.Error()}
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return diagnosis{"connection", statusFail, err.Error()}
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return diagnosis{"connection", statusOK, baseURL}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return diagnosis{"connection", statusFail, baseURL + ": API key rejected (" + resp.Status + ")"}
+	default:
+		return diagnosis{"connection", statusWarn, baseURL + ": " + resp.Status}
+	}
+}
+
+// checkTools checks the shell and the definitions of the enabled tools.
+func checkTools(config cfg.Config) []diagnosis {
+	var results []diagnosis
+	if config.Shell != "" {
+		shell := tools.ResolveShell(config.Shell)
+		if path, err := exec.LookPath(shell); err != nil {
+			results = append(results, diagnosis{"shell", statusFail, "not found: " + shell})
+		} else {
+			results = append(results, diagnosis{"shell", statusOK, path})
+		}
+	}
+
+	registry := tools.NewRegistry()
+	setupTools(config, registry)
+	for _, def := range registry.GetDefinitions() {
+		if err := def.Validate(); err != nil {
+			results = append(results, diagnosis{"tool", statusFail, err.Error()})
+		} else {
+			results = append(results, diagnosis{"tool", statusOK, def.Name})
+		}
+	}
+	return results
+}
@@ -35,7 +35,7 @@ func PromptUser(model string, args []string) (string, error) {
 	if len(args) > 0 { // Message provided as command line arguments
 		return strings.Join(args, " "), nil
 	} else if editor != "" { // Editor specified, open it for composition
-		return editorPrompt(editor)
+		return editorPrompt(editor, "")
 	} else if isTerm { // Running in terminal, prompt interactively
 		return terminalPrompt(model)
 	}
@@ -47,13 +47,13 @@ func PromptUser(model string, args []string) (string, error) {
 // ============================================================================
 
 // editorPrompt opens an external text editor for the user to compose a message.
-// It creates a temporary file, launches the specified editor with that file,
-// and then reads the contents after the editor closes.
+// It creates a temporary file with the initial text, launches the specified
+// editor with that file, and then reads the contents after the editor closes.
 //
 // This function allows for a more comfortable editing experience when composing
 // longer or more complex messages, taking advantage of the user's preferred
 // text editor with all its features (syntax highlighting, keyboard shortcuts, etc.)
-func editorPrompt(editor string) (string, error) {
+func editorPrompt(editor string, initial string) (string, error) {
 	// Create a temporary file for the editor to use
 	tmpDir := os.TempDir()
 	tmp, err := os.CreateTemp(tmpDir, "chat-input-*.md")
@@ -62,6 +62,15 @@ func editorPrompt(editor string) (string, error) {
 	}
 	defer os.Remove(tmp.Name())
 
+	// Prefill the file with the initial text
+	_, err = tmp.WriteString(initial)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("editor temp file: %w", err)
+	}
+
 	// launch editor
 	cmd := exec.Command(editor, tmp.Name())
 	cmd.Stdin = os.Stdin
@@ -17,8 +17,75 @@ Learn more about a command:
 	// MSG_DESC is the description for the msg command
 	MSG_DESC = `Send a message to an LLM model.`
 
+	// RUN_DESC is the description for the run and prompts commands
+	RUN_DESC = `Run prompt templates from the template library.
+
+Templates are Markdown files in .gptx.d/prompts in the current directory
+and its parents, and in prompts in the app config directory. Nearer
+templates hide those of the same name further away.
+
+Templates are Go text/template files with optional front matter:
+
+    ---
+    description: Explain a file
+    model: gpt-4.1
+    temp: 0.2
+    tools: [shell, web]
+    files: ["{{.file}}"]
+    ---
+    Explain {{.file}} on branch {{.Git.Branch}}.
+
+Front matter keys: description, model, temp, reason, max, system, tools
+(shell, web) and files (globs). Flags take precedence over front matter.
+
+Variables are set with --var name=value or name=value arguments. Templates
+can also use the environment ({{.Env.HOME}}), the repository
+({{.Git.Root}}, .Git.Branch, .Git.Commit, .Git.User) and piped
+input ({{.Stdin}}). Missing variables are errors.
+
+Examples:
+    # List templates
+    gptx prompts
+
+    # Run a template with a variable
+    gptx run explain file=main.go
+
+    # Pipe input to a template
+    git log -5 | gptx run summarize`
+
+	// COMMIT_DESC is the description for the commit command
+	COMMIT_DESC = `Write a commit message for the staged changes.
+
+The model is given the staged diff and the recent commits, and replies
+with a message in the selected style. The message is printed, reviewed in
+the editor with --edit, or committed directly with --apply.
+
+Examples:
+    # Print a conventional commit message
+    gptx commit
+
+    # Review a gitmoji message in the editor, then commit
+    gptx commit --style gitmoji --edit --apply`
+
+	// REVIEW_DESC is the description for the review command
+	REVIEW_DESC = `Review changes and report findings.
+
+The diff is split per file, and per hunk for large files. The model reports
+findings for each part, which are printed compiler-style for editors
+(file:line: severity: message) or as SARIF for CI annotations.
+
+Examples:
+    # Review the working tree
+    gptx review
+
+    # Review staged changes
+    gptx review --staged
+
+    # Review a branch and fail CI on errors
+    gptx review --format sarif --fail main..HEAD > review.sarif`
+
 	// CONFIG_DESC is the description for the config command
-	CONFIG_DESC = `Display the configuration.
+	CONFIG_DESC = `Show and edit the configuration.
 
 Configuration sources (in order of precedence):
 1. Command-line flags
@@ -27,17 +94,205 @@ Configuration sources (in order of precedence):
 4. Global config file
 5. Default values
 
-Output is in dotenv format suitable for config files.
+Config files are dotenv files (.gptx, config) or TOML files (.gptx.toml,
+config.toml). TOML keys are the environment variables without the GPTX_
+prefix, in lowercase:
+
+    model = "gpt-4.1"
+    files = ["*.go", "README.md"]
+    web_search = true
+
+    [profile.deep]
+    inherits = "fast"
+    reason = "high"
+
+Profiles are named sections in config files that override the values
+outside of sections. A profile can inherit from another profile:
+
+    GPTX_MODEL=o4-mini
+
+    [profile.fast]
+    GPTX_MODEL=gpt-4.1-mini
+
+    [profile.deep]
+    INHERITS=fast
+    GPTX_REASON=high
+
+Select a profile with --profile or GPTX_PROFILE.
+
+Hooks, the key command and the base URL run commands or choose where the
+API key is sent, so only flags, the environment and the global config can
+set them. .gptx files in the current directory or its parents, which come
+with cloned repositories, are ignored for them with a warning.
+
+Output lists every setting in dotenv format, with the source of its value
+as a comment: flag, env, a config file (and profile), or default. The API
+key is redacted.
+
+Settings are edited with get, set and unset. Keys are environment variables,
+config file keys or flag names. Edits apply to the current directory's
+config file, or the global config file with --global, outside of profile
+sections. Comments and the rest of the file are kept.
+
+The system prompt is assembled in layers:
+1. The configured prompt (--prompt)
+2. instructions.md in the app config directory
+3. AGENTS.md and .gptx.d/instructions.md files, from the root directory
+   down to the current directory
+4. Additions for the run (--add-prompt)
+5. The environment: OS, shell, working directory, date and tools
+
+Print the assembled prompt with --show-prompt.
+
+Examples:
+    # Show where each value comes from
+    gptx cfg
+
+    # Show the system prompt sent to the model
+    gptx cfg --show-prompt --shell auto
+
+    # Show the values of a profile
+    gptx cfg --profile deep
+
+    # Set the project's model
+    gptx cfg set model gpt-4.1
+
+    # Read the API key from a password manager
+    gptx cfg set --global key-cmd "pass show openai"
+
+    # Remove a setting and print its new effective value
+    gptx cfg unset model && gptx cfg get model`
+
+	// AUTH_DESC is the description for the auth command
+	AUTH_DESC = `Manage provider API keys in the OS keyring.
+
+Keys are stored per provider (--provider), and per host for a custom base
+URL (--base-url), in the Secret Service on Linux (through secret-tool) or
+the login keychain on macOS. Keeping keys in the keyring avoids committing
+them to .gptx files.
+
+The API key is resolved in order from:
+1. --key, GPTX_API_KEY or config files
+2. The output of GPTX_API_KEY_CMD (e.g. "pass show openai"), run once
+3. The OS keyring
+4. The provider's environment variable (e.g. OPENAI_API_KEY)
+
+Examples:
+    # Store the OpenAI API key (prompts without echo, or reads stdin)
+    gptx auth login
+
+    # Show where the key comes from
+    gptx auth status
+
+    # Read the key from a password manager instead
+    gptx cfg set --global key-cmd "pass show openai"`
+
+	// DOCTOR_DESC is the description for the doctor command
+	DOCTOR_DESC = `Diagnose the configuration and the connection to the API.
+
+Checks:
+- Config files parse without errors or unknown keys
+- The selected profile and its parents exist
+- Settings are valid and supported by the model (temperature, reasoning,
+  images, tools, web search and max output tokens)
+- An API key is available, and where it comes from
+- The base URL is reachable and accepts the key
+- The shell and the enabled tools are usable
+
+Exits with an error if any check fails.`
+
+	// FILES_DESC is the description for the files command
+	FILES_DESC = `Manage attachments uploaded through the Files API.
+
+Images and PDFs larger than --upload-size are uploaded once and referenced
+by file ID on later runs. Uploads are cached by content hash, per base URL
+and API key, in the app config directory. Files deleted from the provider
+are uploaded again. The commands below manage the current account's uploads.
+
+Examples:
+    # List cached uploads
+    gptx files list
+
+    # Delete uploads unused for a week
+    gptx files prune --age 168h
+
+    # Delete all uploads
+    gptx files prune --all`
+
+	// AUDIT_DESC is the description for the audit command
+	AUDIT_DESC = `Show the audit log of tool calls.
+
+With --audit (or GPTX_AUDIT=true), every tool call a model makes is
+appended to the audit log in the app config directory, with its arguments,
+working directory and project, the decision of pre_tool hooks (allowed,
+rewritten or rejected), its exit status and duration, and a SHA-256 hash of
+its output. With --audit-transcripts, the messages of every request and
+response are recorded too. The log is off by default.
+
+The API key, --scrub patterns and common credentials are redacted. The
+log rotates once it exceeds --audit-size, keeping --audit-keep rotated
+files.
+
+Examples:
+    # Show what ran in this project during the last week
+    gptx audit --project . --since 7d
+
+    # Show shell commands of a day as JSON
+    gptx audit --tool shell --since 2025-06-01 --until 2025-06-02 --json
+
+    # Record full transcripts of a session
+    GPTX_AUDIT=true GPTX_AUDIT_TRANSCRIPTS=true gptx msg "Clean up the build"`
+
+	// CACHE_DESC is the description for the cache command
+	CACHE_DESC = `Manage the response cache.
+
+With --cache (or GPTX_CACHE=true), responses are stored in the app config
+directory and identical requests are answered from the cache, replaying
+the streamed reply with zero token usage. Requests are identical when their
+model, instructions, messages, attached file contents, tools and parameters
+match. Responses with tool calls are not cached.
+
+Entries expire after --cache-ttl, and the least recently used entries are
+evicted once the cache exceeds --cache-size. Bypass the cache for a run
+with --no-cache.
+
+Examples:
+    # Cache responses for a CI job for a week
+    GPTX_CACHE=true GPTX_CACHE_TTL=168h gptx msg "Summarize the API"
+
+    # Show the cache's size and hits
+    gptx cache stats
+
+    # Delete all cached responses
+    gptx cache clear`
+
+	// MODELS_DESC is the description for the models command
+	MODELS_DESC = `List the models available from the provider.
+
+Models are listed with the API key and merged with the known capabilities
+of each model. Capabilities of unknown models are shown as "?". Use
+--known to list the models with known capabilities without the provider.
+
+Requests are adapted to the selected model: parameters it does not
+support are dropped, such as temperatures for reasoning models, and output
+limits are capped at its maximum. Unknown models are sent the parameters
+as configured.
+
+Reasoning models accept a reasoning effort (--reason low|medium|high) and
+report a summary of their reasoning (--reasoning-summary).
+
+Model names complete in the shell after --model once completion is
+enabled (see gptx completion --help).
 
 Examples:
-    # Save current configuration to a file
-    gptx config > .gptx
+    # List GPT-4 models as JSON
+    gptx models gpt-4 --json
 
-    # Create a configuration with specific files
-    gptx --files="*.go" config > .gptx
+    # List models that can reason and search the web
+    gptx models --supports reasoning --supports web-search
 
-    # Create a project-specific configuration
-    gptx --model="gpt-4o" --files="project/*.go" config > project/.gptx`
+    # Reason harder with a reasoning model
+    gptx --model o3 --reason high msg "Plan the migration"`
 
 	// DEMO_DESC is the description for the demo command
 	DEMO_DESC = `Demonstrate the UI and logging capabilities.
@@ -61,7 +61,7 @@ var (
 // This is typically used for the main output of commands that are meant
 // to be consumed by both users and other programs (e.g., model responses).
 func Print(msg any, args ...any) {
-	fmt.Fprintf(os.Stdout, "%s", logMsg(msg, args))
+	write(os.Stdout, logMsg(msg, args))
 }
 
 // PrintErr logs a message to stderr without any formatting or severity prefix.
@@ -69,7 +69,7 @@ func Print(msg any, args ...any) {
 // This is useful for error messages that might be parsed by other programs
 // without the color coding and prefixes of the Error() function.
 func PrintErr(msg any, args ...any) {
-	fmt.Fprintf(os.Stderr, "%s", logMsg(msg, args))
+	write(os.Stderr, logMsg(msg, args))
 }
 
 // Error logs an error message to stderr with red "error:" prefix.
@@ -77,7 +77,7 @@ func PrintErr(msg any, args ...any) {
 // since they represent critical issues that must be addressed.
 // It accepts either a string format with args or an error object.
 func Error(msg any, args ...any) {
-	fmt.Fprintf(os.Stderr, errMsgStr, logMsg(msg, args))
+	write(os.Stderr, fmt.Sprintf(errMsgStr, logMsg(msg, args)))
 }
 
 // Warn logs a warning message to stderr with yellow "warn:" prefix.
@@ -89,7 +89,13 @@ func Warn(msg any, args ...any) {
 	if silent {
 		return
 	}
-	fmt.Fprintf(os.Stderr, warnMsgStr, logMsg(msg, args))
+	write(os.Stderr, fmt.Sprintf(warnMsgStr, logMsg(msg, args)))
+}
+
+// warnf logs a formatted warning, for packages that report through a
+// printf-style function.
+func warnf(format string, args ...any) {
+	Warn(format, args...)
 }
 
 // Info logs an informational message to stderr with blue "info:" prefix.
@@ -101,7 +107,7 @@ func Info(msg any, args ...any) {
 	if silent {
 		return
 	}
-	fmt.Fprintf(os.Stderr, infoMsgStr, logMsg(msg, args))
+	write(os.Stderr, fmt.Sprintf(infoMsgStr, logMsg(msg, args)))
 }
 
 // Debug logs a debug message if the current log level permits.
@@ -110,7 +116,7 @@ func Debug(msg any, args ...any) {
 	if silent || !verbose {
 		return
 	}
-	fmt.Fprintf(os.Stderr, debugMsgStr, logMsg(msg, args))
+	write(os.Stderr, fmt.Sprintf(debugMsgStr, logMsg(msg, args)))
 }
 
 // MARK: Flags
@@ -150,6 +156,12 @@ var (
 // MARK: Helpers
 // ============================================================================
 
+// write prints text to a file in place of the status line.
+func write(file *os.File, text string) {
+	status.printed(file, text)
+	fmt.Fprint(file, text)
+}
+
 func logMsg(msg any, args []any) string {
 	var text string
 	switch m := msg.(type) {
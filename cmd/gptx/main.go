@@ -22,13 +22,62 @@ func main() {
 
 	cmd.Commands = []*cli.Command{
 		msgCMD(config),
-		configCMD(),
+		runCMD(config),
+		promptsCMD(),
+		commitCMD(config),
+		reviewCMD(config),
+		configCMD(config),
+		authCMD(config),
+		doctorCMD(config),
+		filesCMD(config),
+		cacheCMD(config),
+		auditCMD(config),
+		modelsCMD(config),
 		demoCMD(),
 	}
 
+	// Complete --model values in every command
+	for _, command := range allCommands(cmd) {
+		command.ShellComplete = completeModels()
+	}
+
+	// Load config files for the selected profile
+	profile := cfg.ProfileArg(os.Args[1:])
+	warnings, err := cfg.LoadConfigFiles(profile, cfg.FlagKeys(allFlags(cmd)))
+
+	// Report config warnings once logging flags are parsed
+	cmd.Before = func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+		for _, warning := range warnings {
+			Warn(warning)
+		}
+		// The doctor diagnoses config errors instead
+		if err != nil && cmd.Args().First() != "doctor" {
+			return ctx, err
+		}
+		return ctx, nil
+	}
+
 	// run the app
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		Error(err)
 		os.Exit(1)
 	}
 }
+
+// allCommands returns a command and all of its subcommands.
+func allCommands(cmd *cli.Command) []*cli.Command {
+	commands := []*cli.Command{cmd}
+	for _, sub := range cmd.Commands {
+		commands = append(commands, allCommands(sub)...)
+	}
+	return commands
+}
+
+// allFlags returns the flags of a command and all of its subcommands.
+func allFlags(cmd *cli.Command) []cli.Flag {
+	flags := cmd.Flags
+	for _, sub := range cmd.Commands {
+		flags = append(flags, allFlags(sub)...)
+	}
+	return flags
+}
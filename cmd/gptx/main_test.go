@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites golden files with the current output.
+var update = flag.Bool("update", false, "update golden files")
+
+// runMainEnv makes the test binary run the CLI instead of the tests.
+const runMainEnv = "GPTX_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI runs the CLI with args in an empty directory, without the user's
+// config or environment settings, and returns its output.
+func runCLI(t *testing.T, args ...string) (stdout, stderr string) {
+	t.Helper()
+	return runCLIIn(t, t.TempDir(), args...)
+}
+
+// runCLIIn runs the CLI like runCLI, in dir.
+func runCLIIn(t *testing.T, dir string, args ...string) (stdout, stderr string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "GPTX_") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Env = append(cmd.Env, runMainEnv+"=1", "HOME="+dir, "XDG_CONFIG_HOME="+dir)
+
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("gptx %s: %s\n%s", strings.Join(args, " "), err, errOut.String())
+	}
+	return out.String(), errOut.String()
+}
+
+// TestFakeGolden checks the output of a scripted tool call against its
+// golden file. Run with -update to rewrite it.
+func TestFakeGolden(t *testing.T) {
+	script, err := filepath.Abs("testdata/tool.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := runCLI(t, "msg", "--provider", "fake", "--script", script,
+		"--shell", "sh", "--quiet=false", "greet", "me")
+	got := "-- stdout --\n" + stdout + "\n-- stderr --\n" + stderr
+
+	golden := "testdata/tool.golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s", golden, got)
+	}
+}
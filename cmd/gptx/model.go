@@ -2,39 +2,90 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/mohdfareed/gptx-cli/internal/audit"
+	"github.com/mohdfareed/gptx-cli/internal/auth"
 	"github.com/mohdfareed/gptx-cli/internal/cfg"
 	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/mohdfareed/gptx-cli/internal/hooks"
+	"github.com/mohdfareed/gptx-cli/internal/prompts"
+	"github.com/mohdfareed/gptx-cli/internal/telemetry"
 	"github.com/mohdfareed/gptx-cli/internal/tools"
+	"github.com/mohdfareed/gptx-cli/pkg/cassette"
 	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx/fake"
 	"github.com/mohdfareed/gptx-cli/pkg/openai"
+	"github.com/openai/openai-go/option"
 )
 
 // setupCallbacks configures the event callbacks for the CLI.
 func setupCallbacks() events.Callbacks {
+	// Reasoning streams as a block that ends with the next output
+	var reasoning bool
+	endReasoning := func() {
+		if reasoning {
+			reasoning = false
+			PrintErr("\n\n")
+		}
+	}
+
 	return events.NewCallbacks().
 		// Model lifecycle events
 		WithStartHandler(func(config cfg.Config) {
 			Debug("Model started")
 		}).
 		WithErrorHandler(func(err error) {
+			endReasoning()
 			Error("Model error: %s\n", err)
 		}).
+		WithRetryHandler(func(err error, delay time.Duration) {
+			endReasoning()
+			Warn("Retrying in %s: %s", delay.Round(time.Millisecond), err)
+		}).
+		WithFailoverHandler(func(from, to string, err error) {
+			endReasoning()
+			Warn("Failing over from %s to %s: %s", from, to, err)
+		}).
+		WithRouteHandler(func(backend, reason string) {
+			if reason == "primary" {
+				Debug("Using %s", backend)
+			} else {
+				Info("Using %s: %s", backend, reason)
+			}
+		}).
 		WithDoneHandler(func(usage string) {
+			endReasoning()
 			Info("Usage: %s\n", usage)
 		}).
 		// Output events
 		WithReplyHandler(func(text string) {
+			endReasoning()
 			Print(text)
 		}).
 		WithReasoningHandler(func(text string) {
-			PrintErr(M+"Reasoning: %s\n"+Reset, text)
+			if !reasoning {
+				reasoning = true
+				PrintErr(M + "Reasoning: " + Reset)
+			}
+			PrintErr(M+"%s"+Reset, text)
 		}).
 		// Tool events
 		WithToolCallHandler(func(call tools.ToolCall) {
+			endReasoning()
 			Info(M+"Tool call: %s(%s)\n"+Reset, call.Name, call.Params)
 		}).
+		WithToolProgressHandler(func(name, args string) {
+			endReasoning() // the status line shows the call's progress
+		}).
 		WithToolResultHandler(func(result string) {
 			Info(M+"Tool result: %s\n"+Reset, result)
 		}).
@@ -49,31 +100,338 @@ func setupTools(config cfg.Config, registry *tools.Registry) {
 	}
 }
 
-// createModel creates a new model with the given configuration.
-func createModel(config cfg.Config) *gptx.Model {
-	// Create the callbacks manager
-	callbacks := setupCallbacks()
+// systemPrompt composes the system prompt for the config and its tools.
+func systemPrompt(config cfg.Config, registry *tools.Registry) (string, error) {
+	var names []string
+	for _, def := range registry.GetDefinitions() {
+		names = append(names, def.Name)
+	}
+	if config.WebSearch {
+		names = append(names, "web search")
+	}
+	slices.Sort(names)
+	return prompts.System(config, names)
+}
+
+// setupContext creates the context providers enabled in the config.
+func setupContext(config cfg.Config) ([]gptx.ContextProvider, error) {
+	var providers []gptx.ContextProvider
+	if config.Diff {
+		providers = append(providers, git.WorkingDiff())
+	}
+	if config.Staged {
+		providers = append(providers, git.StagedDiff())
+	}
+	if config.DiffRef != "" {
+		providers = append(providers, git.RefDiff(config.DiffRef))
+	}
+	if config.Log > 0 {
+		providers = append(providers, git.Log(config.Log))
+	}
+	for _, spec := range config.Blame {
+		blame, err := git.Blame(spec)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, blame)
+	}
+	return providers, nil
+}
+
+// withoutContext returns the config without its context providers, for
+// commands that attach their own context.
+func withoutContext(config cfg.Config) cfg.Config {
+	config.Diff, config.Staged, config.DiffRef = false, false, ""
+	config.Log, config.Blame = 0, nil
+	return config
+}
+
+// createClient creates an OpenAI client with the given configuration.
+// Additional SDK options are applied after the configured ones.
+func createClient(
+	ctx context.Context, config cfg.Config, opts ...option.RequestOption,
+) (*openai.OpenAIClient, error) {
+	// The key is only needed to call the API
+	key, source, err := auth.Lookup(ctx, config.Provider, config.BaseURL, config.APIKey, config.KeyCmd)
+	if errors.Is(err, auth.ErrNoKey) && config.Replay != "" {
+		key, source, err = cassette.Redacted, "cassette", nil // not sent
+	}
+	if errors.Is(err, auth.ErrNoKey) {
+		return nil, fmt.Errorf("%w: use gptx auth login, --key or %sAPI_KEY", err, cfg.EnvVarPrefix)
+	} else if err != nil {
+		return nil, err
+	}
+	Debug("Using %s API key from %s", config.Provider, source)
+
+	if config.BaseURL != "" {
+		opts = append([]option.RequestOption{option.WithBaseURL(config.BaseURL)}, opts...)
+	}
+
+	// Record or replay the API's exchanges
+	transport, err := cassetteTransport(config, key)
+	if err != nil {
+		return nil, err
+	} else if transport != nil {
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+	client := openai.NewOpenAIClient(key, opts...)
+
+	// Reuse uploaded attachments across runs
+	var cachePath string
+	if cfg.AppDir != "" {
+		cachePath = filepath.Join(cfg.AppDir, "uploads.json")
+	}
+	uploads, err := openai.LoadUploadCache(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	return client.WithUploads(uploads, config.BaseURL, int64(config.Uploads)*1024), nil
+}
+
+// createBackends creates the backends of the configured model and its
+// fallbacks. Each provider's client retries its failed requests, through
+// middleware rather than the SDK, so that interrupted streams are retried.
+func createBackends(ctx context.Context, config cfg.Config) ([]gptx.Backend, error) {
+	policy := gptx.DefaultRetryPolicy
+	policy.Attempts = config.Retries
+
+	clients := map[string]gptx.Client{}
+	var backends []gptx.Backend
+	for _, name := range backendNames(config) {
+		provider, model := backendName(config, name)
+		client, ok := clients[provider]
+		if !ok {
+			// Credentials and endpoints are those of the configured provider
+			providerConfig := config
+			if provider != config.Provider {
+				providerConfig.Provider = provider
+				providerConfig.APIKey, providerConfig.KeyCmd, providerConfig.BaseURL = "", "", ""
+			}
+			providerClient, err := createProviderClient(ctx, providerConfig)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", provider, err)
+			}
+			client = gptx.Chain(providerClient, gptx.Retry(policy),
+				gptx.Logging(func(format string, args ...any) { Debug(format, args...) }))
+			clients[provider] = client
+		}
+		backends = append(backends, gptx.Backend{Provider: provider, Model: model, Client: client})
+	}
+	return backends, nil
+}
+
+// backendNames returns the configured model and its fallbacks, in order.
+func backendNames(config cfg.Config) []string {
+	return append([]string{config.Provider + "/" + config.Model}, config.Fallback...)
+}
+
+// backendName returns the provider and model of a [provider/]model name,
+// which defaults to the configured provider.
+func backendName(config cfg.Config, name string) (string, string) {
+	if prefix, rest, ok := strings.Cut(name, "/"); ok {
+		if slices.Contains(cfg.Providers, prefix) {
+			return prefix, rest
+		}
+	}
+	return config.Provider, name
+}
+
+// apiKeys returns the API keys of the providers of the model and its
+// fallbacks that can be resolved, so that they can be redacted.
+func apiKeys(ctx context.Context, config cfg.Config) []string {
+	var keys []string
+	for _, name := range backendNames(config) {
+		provider, _ := backendName(config, name)
+		if provider == "fake" {
+			continue
+		}
+		baseURL, key, command := "", "", ""
+		if provider == config.Provider {
+			baseURL, key, command = config.BaseURL, config.APIKey, config.KeyCmd
+		}
+		if key, _, err := auth.Lookup(ctx, provider, baseURL, key, command); err == nil &&
+			!slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// createProviderClient creates the client of the configured provider.
+func createProviderClient(ctx context.Context, config cfg.Config) (gptx.Client, error) {
+	if config.Provider == "fake" {
+		Debug("Replying from script %s", config.Script)
+		return fake.Load(config.Script)
+	}
+	return createClient(ctx, config, option.WithMaxRetries(0))
+}
+
+// responseCache returns the response cache in the app directory.
+func responseCache(config cfg.Config) *gptx.Cache {
+	dir := filepath.Join(cfg.AppDir, "cache")
+	return gptx.NewCache(dir, config.CacheTTL, int64(config.CacheSize)*1024*1024,
+		gptx.WithKeyNormalizer(func(request gptx.Request) gptx.Request {
+			// Requests hit across directories and dates
+			request.Config.SysPrompt = prompts.WithoutEnvironment(request.Config.SysPrompt)
+			return request
+		}))
+}
+
+// cassettes are the cassette transports by file, shared by the clients of
+// a run so that they record into the same cassette.
+var cassettes = map[string]http.RoundTripper{}
+
+// cassetteTransport returns the transport that records or replays the
+// configured cassette, if any. The API key is scrubbed from recordings.
+func cassetteTransport(config cfg.Config, key string) (http.RoundTripper, error) {
+	path := config.Replay
+	if path == "" {
+		path = config.Record
+	}
+	if path == "" {
+		return nil, nil
+	} else if transport, ok := cassettes[path]; ok {
+		return transport, nil
+	}
+
+	var transport http.RoundTripper
+	if config.Replay != "" {
+		replayer, err := cassette.NewReplayer(path)
+		if err != nil {
+			return nil, err
+		}
+		transport = replayer
+		Debug("Replaying API exchanges from %s", path)
+	} else {
+		scrubber, err := cassette.NewScrubber([]string{key}, config.Scrub)
+		if err != nil {
+			return nil, err
+		}
+		transport = cassette.NewRecorder(path, nil, scrubber, warnf)
+		Debug("Recording API exchanges into %s", path)
+	}
+	cassettes[path] = transport
+	return transport, nil
+}
+
+// createModel creates a new model with the given configuration and callbacks.
+// Additional options are applied after the defaults.
+func createModel(
+	ctx context.Context, config cfg.Config,
+	callbacks events.Callbacks, options ...gptx.ModelOption,
+) (*gptx.Model, error) {
+	// Report invalid settings before any request is sent
+	if err := gptx.ValidateConfig(config); err != nil {
+		return nil, err
+	}
 
 	// Create the tool registry
 	registry := tools.NewRegistry()
 	setupTools(config, registry)
 
-	// Create and configure the client
-	client := openai.NewOpenAIClient(config.APIKey)
+	// Compose the system prompt from its layers
+	prompt, err := systemPrompt(config, registry)
+	if err != nil {
+		return nil, err
+	}
+	config.SysPrompt = prompt
+
+	// Create the clients of the model and its fallbacks
+	backends, err := createBackends(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	client := backends[0].Client
+	if len(backends) > 1 {
+		client = gptx.NewRouter(backends, config.Route)
+	}
+
+	// Record tool calls, and optionally every request, in the audit log
+	var middlewares []gptx.Middleware
+	if config.Audit && cfg.AppDir != "" {
+		log, err := auditLog(config, apiKeys(ctx, config)...)
+		if err != nil {
+			return nil, err
+		}
+		recorder := audit.NewRecorder(ctx, log, warnf)
+		options = append(options,
+			gptx.WithHandler(recorder), gptx.WithToolHook(recorder.ToolHook))
+		if config.AuditTranscripts {
+			middlewares = append(middlewares, recorder.Transcripts)
+		}
+	}
+
+	// Cache hits skip the backends entirely
+	if config.Cache && cfg.AppDir != "" {
+		middlewares = append(middlewares, gptx.Cached(responseCache(config)))
+	}
+
+	// Create the context providers
+	providers, err := setupContext(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the configured hooks on the model's events
+	hookList, err := hooks.Parse(config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	if len(hookList) > 0 {
+		runner := hooks.NewRunner(hookList, warnf)
+		options = append(options,
+			gptx.WithHandler(runner), gptx.WithToolHook(runner.PreTool))
+	}
+
+	// Trace and measure the model's runs
+	if config.OTLPEndpoint != "" || config.TelemetryFile != "" {
+		handler, err := createTelemetry(config)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, gptx.WithHandler(handler))
+	}
 
 	// Create the model
-	model := gptx.NewModel(
-		config, registry,
+	options = append([]gptx.ModelOption{
 		gptx.WithClient(client),
+		gptx.WithMiddleware(middlewares...),
 		gptx.WithCallbacks(callbacks),
-	)
+		gptx.WithContext(providers...),
+	}, options...)
+	model := gptx.NewModel(config, registry, options...)
 
-	return model
+	return model, nil
+}
+
+// createTelemetry creates the telemetry of models, exported to the
+// configured collector and file.
+func createTelemetry(config cfg.Config) (*telemetry.Telemetry, error) {
+	var exporters []telemetry.Exporter
+	if config.OTLPEndpoint != "" {
+		exporter, err := telemetry.NewOTLP(config.OTLPEndpoint, config.OTLPHeaders)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.TelemetryFile != "" {
+		exporters = append(exporters, telemetry.NewFile(config.TelemetryFile))
+	}
+
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	return telemetry.New(cfg.AppName, version, warnf, exporters...), nil
 }
 
 // runModel runs a conversation with the given model and prompt.
 func runModel(ctx context.Context, config cfg.Config, prompt string) error {
-	model := createModel(config)
+	model, err := createModel(ctx, config, setupCallbacks(), gptx.WithHandler(status))
+	if err != nil {
+		return err
+	}
 	if err := model.Message(ctx, prompt); err != nil {
 		return fmt.Errorf("model error: %w", err)
 	}
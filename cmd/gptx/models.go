@@ -0,0 +1,189 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/urfave/cli/v3"
+)
+
+// capabilityFilters select models by capability for --supports.
+var capabilityFilters = map[string]func(gptx.Capabilities) bool{
+	"temperature": func(c gptx.Capabilities) bool { return c.Temperature },
+	"reasoning":   func(c gptx.Capabilities) bool { return c.Reasoning },
+	"images":      func(c gptx.Capabilities) bool { return c.Images },
+	"tools":       func(c gptx.Capabilities) bool { return c.Tools },
+	"web-search":  func(c gptx.Capabilities) bool { return c.WebSearch },
+}
+
+// MARK: Command
+// ============================================================================
+
+// modelsCMD creates the models command for listing available models.
+func modelsCMD(config *cfg.Config) *cli.Command {
+	var filters, supports []string
+	var known, asJSON bool
+
+	return &cli.Command{
+		Name: "models", Usage: "List available models and their capabilities",
+		Description: MODELS_DESC,
+		Arguments: []cli.Argument{
+			&cli.StringArgs{
+				Name: "filter", UsageText: "Show models whose names contain any filter",
+				Destination: &filters, Max: -1,
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name: "supports", Usage: "Show models with a capability (temperature, reasoning, images, tools, web-search)",
+				Destination: &supports,
+				Validator: func(capabilities []string) error {
+					for _, capability := range capabilities {
+						if capabilityFilters[capability] == nil {
+							return fmt.Errorf("unknown capability: %s", capability)
+						}
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name: "known", Usage: "List the models with known capabilities instead",
+				Destination: &known,
+			},
+			&cli.BoolFlag{
+				Name: "json", Usage: "Print models as JSON",
+				Destination: &asJSON,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			models, err := listModels(ctx, *config, known)
+			if err != nil {
+				return fmt.Errorf("models: %w", err)
+			}
+			models = slices.DeleteFunc(models, func(model gptx.ModelInfo) bool {
+				return !matchModel(model, filters, supports)
+			})
+
+			if asJSON {
+				data, err := json.MarshalIndent(models, "", "  ")
+				if err != nil {
+					return fmt.Errorf("models: %w", err)
+				}
+				Print(string(data) + "\n")
+				return nil
+			}
+			printModels(models)
+			return nil
+		},
+	}
+}
+
+// MARK: Models
+// ============================================================================
+
+// listModels lists the provider's models, or the models with known
+// capabilities.
+func listModels(
+	ctx context.Context, config cfg.Config, known bool,
+) ([]gptx.ModelInfo, error) {
+	if !known {
+		client, err := createClient(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return gptx.ListModels(ctx, client)
+	}
+
+	var models []gptx.ModelInfo
+	for _, name := range gptx.KnownModels() {
+		caps, _ := gptx.ModelCapabilities(name)
+		models = append(models, gptx.ModelInfo{ID: name, Capabilities: &caps})
+	}
+	return models, nil
+}
+
+// matchModel reports whether a model's name contains any of the filters
+// and it has all of the capabilities.
+func matchModel(model gptx.ModelInfo, filters, supports []string) bool {
+	if len(filters) > 0 && !slices.ContainsFunc(filters, func(filter string) bool {
+		return strings.Contains(model.ID, filter)
+	}) {
+		return false
+	}
+	for _, capability := range supports {
+		if model.Capabilities == nil || !capabilityFilters[capability](*model.Capabilities) {
+			return false
+		}
+	}
+	return true
+}
+
+// printModels prints models as a table. Unknown capabilities are shown
+// as question marks.
+func printModels(models []gptx.ModelInfo) {
+	yes := func(supported bool) string {
+		if supported {
+			return "yes"
+		}
+		return "-"
+	}
+
+	format := "%-28s %-6s %-10s %-7s %-6s %-4s %s\n"
+	Print(Bold+format+Reset,
+		"MODEL", "TEMP", "REASONING", "IMAGES", "TOOLS", "WEB", "MAX OUTPUT")
+	for _, model := range models {
+		caps := model.Capabilities
+		if caps == nil {
+			Print(format, model.ID, "?", "?", "?", "?", "?", "?")
+			continue
+		}
+		Print(format, model.ID, yes(caps.Temperature), yes(caps.Reasoning),
+			yes(caps.Images), yes(caps.Tools), yes(caps.WebSearch),
+			fmt.Sprint(caps.MaxOutputTokens))
+	}
+}
+
+// MARK: Completion
+// ============================================================================
+
+// completeModels completes the values of --model with the provider's
+// models, or the known models if they cannot be listed. Other completions
+// are left to the default.
+func completeModels() cli.ShellCompleteFunc {
+	return func(ctx context.Context, cmd *cli.Command) {
+		// The completion flag follows the flag being completed
+		args := os.Args
+		if len(args) < 2 || args[len(args)-2] != "--model" {
+			cli.DefaultCompleteWithFlags(ctx, cmd)
+			return
+		}
+
+		// Flags are not parsed while a flag's value is completed, so the
+		// connection settings are read from the environment and config files.
+		// The key command isn't run on a keypress.
+		config := cfg.Config{
+			Provider: cmp.Or(os.Getenv(cfg.EnvVarPrefix+"PROVIDER"), "openai"),
+			APIKey:   os.Getenv(cfg.EnvVarPrefix + "API_KEY"),
+			BaseURL:  os.Getenv(cfg.EnvVarPrefix + "BASE_URL"),
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		models, err := listModels(ctx, config, false)
+		if err != nil {
+			models, _ = listModels(ctx, config, true)
+		}
+		for _, model := range models {
+			fmt.Fprintln(cmd.Root().Writer, model.ID)
+		}
+	}
+}
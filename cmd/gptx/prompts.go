@@ -0,0 +1,125 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/mohdfareed/gptx-cli/internal/prompts"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// runCMD creates the run command for running prompt templates.
+func runCMD(config *cfg.Config) *cli.Command {
+	var args, vars []string
+	return &cli.Command{
+		Name: "run", Usage: "Run a prompt template",
+		Description: RUN_DESC,
+		Arguments: []cli.Argument{
+			&cli.StringArgs{
+				Name: "template", UsageText: "Template name or file, followed by name=value variables",
+				Destination: &args, Min: 1, Max: -1,
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name: "var", Usage: "Set a template variable (name=value)",
+				Destination: &vars,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			tmpl, err := prompts.Find(args[0])
+			if err != nil {
+				return err
+			}
+			data, err := templateData(ctx, append(vars, args[1:]...), tmpl.Uses("Stdin"))
+			if err != nil {
+				return fmt.Errorf("run: %w", err)
+			}
+			tmpl, err = tmpl.Render(data)
+			if err != nil {
+				return err
+			}
+
+			// Flags take precedence over the template's settings
+			config := *config
+			keep := func(name string) bool { return cliFlagSet(cmd, name) }
+			if err := tmpl.Apply(&config, keep); err != nil {
+				return err
+			}
+			Debug("Prompt:\n%s", tmpl.Body)
+			return runModel(ctx, config, tmpl.Body)
+		},
+	}
+}
+
+// promptsCMD creates the prompts command for listing prompt templates.
+func promptsCMD() *cli.Command {
+	return &cli.Command{
+		Name: "prompts", Usage: "List prompt templates",
+		Description: RUN_DESC,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			templates, err := prompts.List()
+			if err != nil {
+				return err
+			}
+			for _, tmpl := range templates {
+				Print("%s%-16s%s %s %s(%s)%s\n", Bold, tmpl.Name, Reset,
+					tmpl.Description, Dim, tmpl.Path, Reset)
+			}
+			if len(templates) == 0 {
+				Info("No prompt templates in %s", strings.Join(prompts.Dirs(), ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// templateData returns the data templates are rendered with: variables,
+// the environment (.Env), repository info (.Git) and piped input (.Stdin).
+// Input is only read if requested, since reading it waits for its end.
+func templateData(
+	ctx context.Context, vars []string, stdin bool,
+) (map[string]any, error) {
+	data := map[string]any{
+		"Env": cfg.EnvMap(),
+		"Git": git.RepoInfo(ctx),
+	}
+
+	data["Stdin"] = ""
+	if stdin && !term.IsTerminal(int(os.Stdin.Fd())) {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		data["Stdin"] = strings.TrimSpace(string(input))
+	}
+
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q: expected name=value", v)
+		}
+		data[name] = value
+	}
+	return data, nil
+}
+
+// cliFlagSet reports whether a root flag was set on the command line.
+func cliFlagSet(cmd *cli.Command, name string) bool {
+	for _, flag := range cmd.Root().Flags {
+		if flag.Names()[0] == name {
+			return flagArg(flag.Names(), os.Args[1:])
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+)
+
+// TestReplay replays a recorded exchange with a tool call through the
+// model, its OpenAI client and the streaming of its responses.
+func TestReplay(t *testing.T) {
+	cfg.AppDir = t.TempDir()
+	clear(cassettes) // Replay the cassette from its start
+	config := cfg.Config{
+		Provider: "openai", Model: "gpt-4.1", APIKey: "sk-test",
+		Summary: "auto", Shell: "sh", Replay: "testdata/replay.json",
+	}
+
+	var reply, reasoning strings.Builder
+	var calls, results []string
+	callbacks := events.Callbacks{
+		OnReply:      func(text string) { reply.WriteString(text) },
+		OnReasoning:  func(text string) { reasoning.WriteString(text) },
+		OnToolCall:   func(call tools.ToolCall) { calls = append(calls, call.Name) },
+		OnToolResult: func(result string) { results = append(results, result) },
+	}
+	model, err := createModel(context.Background(), config, callbacks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Message(context.Background(), "run echo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := reply.String(), "The tool said r-out."; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if got := reasoning.String(); !strings.Contains(got, "Plan A.") || !strings.Contains(got, "Plan B.") {
+		t.Errorf("reasoning = %q, want both summary parts", got)
+	}
+	if len(calls) != 1 || calls[0] != "shell" {
+		t.Errorf("tool calls = %v, want [shell]", calls)
+	}
+	if len(results) != 1 || strings.TrimSpace(results[0]) != "r-out" {
+		t.Errorf("tool results = %q, want [r-out]", results)
+	}
+
+	// The user message, the tool call and its result, and the reply
+	history := model.History()
+	if len(history) != 4 {
+		t.Fatalf("history has %d messages, want 4: %+v", len(history), history)
+	}
+	if call := history[1].ToolCall; call == nil || call.ID != "call_r" {
+		t.Errorf("history[1] = %+v, want the call_r tool call", history[1])
+	}
+	if history[2].CallID != "call_r" {
+		t.Errorf("history[2] = %+v, want the result of call_r", history[2])
+	}
+}
@@ -0,0 +1,272 @@
+// Package main implements the GPTx CLI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/urfave/cli/v3"
+)
+
+// REVIEW_PROMPT is the system prompt for code reviews.
+const REVIEW_PROMPT string = `
+You are a meticulous code reviewer. You are given part of a unified diff.
+Lines of the new file are prefixed with their line numbers.
+
+Report only real problems in the changed lines: bugs, security issues,
+race conditions, error handling, performance, and unclear code.
+Do not report style nits or praise. Report nothing if the change is fine.
+
+For each finding give the file path, the new-file line number, a severity
+(error, warning or note), a concise message, and a concrete suggestion
+(or an empty string if there is none).
+`
+
+// reviewSchema is the JSON schema of the model's review findings.
+var reviewSchema = gptx.Schema{
+	Name: "review",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"findings": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"file":       map[string]any{"type": "string"},
+						"line":       map[string]any{"type": "integer"},
+						"severity":   map[string]any{"type": "string", "enum": []string{"error", "warning", "note"}},
+						"message":    map[string]any{"type": "string"},
+						"suggestion": map[string]any{"type": "string"},
+					},
+					"required":             []string{"file", "line", "severity", "message", "suggestion"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"findings"},
+		"additionalProperties": false,
+	},
+}
+
+// Finding is a single review finding.
+type Finding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// MARK: Command
+// ============================================================================
+
+// reviewCMD creates the review command for reviewing diffs.
+func reviewCMD(config *cfg.Config) *cli.Command {
+	var refs []string
+	var fail bool
+	var format string
+	var chunk int
+
+	return &cli.Command{
+		Name: "review", Usage: "Review changes and report findings",
+		Description: REVIEW_DESC,
+		Arguments: []cli.Argument{
+			&cli.StringArgs{
+				Name: "refs", UsageText: "Refs or range to diff (e.g. main..HEAD)",
+				Destination: &refs, Max: -1,
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "format", Usage: "Output format (text, sarif)",
+				Value: "text", Destination: &format,
+				Validator: func(format string) error {
+					if format != "text" && format != "sarif" {
+						return fmt.Errorf("unknown format: %s", format)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name: "chunk", Usage: "Split file diffs larger than this into hunks (chars)",
+				Value: 12000, Destination: &chunk,
+			},
+			&cli.BoolFlag{
+				Name: "fail", Usage: "Exit with an error if errors are found",
+				Destination: &fail,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			// The global --staged selects the staged changes to review
+			args := []string{"diff", "--no-color", "--no-ext-diff"}
+			if config.Staged {
+				args = append(args, "--staged")
+			}
+			args = append(append(args, "--end-of-options"), refs...)
+			diff, err := git.Run(ctx, append(args, "--")...)
+			if err != nil {
+				return fmt.Errorf("review: %w", err)
+			}
+
+			findings, err := review(ctx, *config, diff, chunk)
+			if err != nil {
+				return fmt.Errorf("review: %w", err)
+			}
+
+			if format == "sarif" {
+				data, err := sarifLog(findings)
+				if err != nil {
+					return fmt.Errorf("review: %w", err)
+				}
+				Print(string(data) + "\n")
+			} else {
+				printFindings(findings)
+			}
+
+			for _, finding := range findings {
+				if fail && finding.Severity == "error" {
+					return fmt.Errorf("review: found errors")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// MARK: Review
+// ============================================================================
+
+// review asks the model for findings on each chunk of a diff.
+func review(
+	ctx context.Context, config cfg.Config, diff string, chunk int,
+) ([]Finding, error) {
+	files, err := git.ParseDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the diff is relevant
+	config.SysPrompt = REVIEW_PROMPT
+	config.Files, config.Shell, config.WebSearch = nil, "", false
+	config = withoutContext(config)
+	callbacks := events.NewCallbacks().
+		WithErrorHandler(func(err error) {
+			Debug("Model error: %s", err)
+		}).
+		WithRetryHandler(func(err error, delay time.Duration) {
+			Info("Retrying in %s: %s", delay.Round(time.Millisecond), err)
+		}).
+		WithDoneHandler(func(usage string) {
+			Debug("Usage: %s", usage)
+		}).
+		Build()
+
+	var findings []Finding
+	for _, file := range files {
+		for _, part := range reviewChunks(file, chunk) {
+			Info("Reviewing %s", file.Path)
+			model, err := createModel(ctx, config, callbacks, gptx.WithSchema(reviewSchema),
+				gptx.WithHandler(status))
+			if err != nil {
+				return nil, err
+			}
+			model.AttachContext(gptx.Attachments{{
+				Label: "git diff " + file.Path, Content: part,
+			}})
+
+			prompt := "Review the changes to " + file.Path + "."
+			if err := model.Message(ctx, prompt); err != nil {
+				return nil, fmt.Errorf("model error: %w", err)
+			}
+
+			var reply struct{ Findings []Finding }
+			if err := json.Unmarshal([]byte(lastReply(model.History())), &reply); err != nil {
+				return nil, fmt.Errorf("findings for %s: %w", file.Path, err)
+			}
+			findings = append(findings, reply.Findings...)
+		}
+	}
+	return findings, nil
+}
+
+// reviewChunks splits a file's patch into chunks of numbered hunks.
+// Files that fit in a chunk are reviewed whole; larger ones per hunk.
+func reviewChunks(file git.FileDiff, size int) []string {
+	var hunks []string
+	for _, hunk := range file.Hunks {
+		hunks = append(hunks, hunk.Numbered())
+	}
+	if len(hunks) == 0 {
+		return nil // nothing to review (binary files, renames)
+	}
+
+	whole := file.Header + "\n" + strings.Join(hunks, "\n")
+	if len(whole) <= size {
+		return []string{whole}
+	}
+
+	chunks := make([]string, len(hunks))
+	for i, hunk := range hunks {
+		chunks[i] = file.Header + "\n" + hunk
+	}
+	return chunks
+}
+
+// MARK: Output
+// ============================================================================
+
+// printFindings prints findings in compiler style: file:line: severity: msg
+func printFindings(findings []Finding) {
+	colors := map[string]string{"error": R, "warning": Y, "note": B}
+	for _, f := range findings {
+		severity := Bold + colors[f.Severity] + f.Severity + Reset
+		Print("%s:%d: %s: %s\n", f.File, f.Line, severity, f.Message)
+		if f.Suggestion != "" {
+			Print(Dim+"    suggestion: %s\n"+Reset, f.Suggestion)
+		}
+	}
+}
+
+// sarifLog returns findings as a SARIF 2.1.0 log.
+func sarifLog(findings []Finding) ([]byte, error) {
+	results := []map[string]any{}
+	for _, f := range findings {
+		result := map[string]any{
+			"ruleId":  cfg.AppName + "-review",
+			"level":   f.Severity,
+			"message": map[string]any{"text": f.Message},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": f.File},
+					"region":           map[string]any{"startLine": max(f.Line, 1)},
+				},
+			}},
+		}
+		if f.Suggestion != "" {
+			result["properties"] = map[string]any{"suggestion": f.Suggestion}
+		}
+		results = append(results, result)
+	}
+
+	log := map[string]any{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": []map[string]any{{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           cfg.AppName,
+				"informationUri": "https://github.com/mohdfareed/gptx-cli",
+			}},
+			"results": results,
+		}},
+	}
+
+	return json.MarshalIndent(log, "", "  ")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/internal/git"
+)
+
+func TestReviewChunks(t *testing.T) {
+	file := git.FileDiff{
+		Path: "main.go", Header: "diff --git a/main.go b/main.go",
+		Hunks: []git.Hunk{
+			{Header: "@@ -1 +1 @@", Start: 1, Lines: []string{"-a", "+b"}},
+			{Header: "@@ -9 +9 @@", Start: 9, Lines: []string{"-c", "+d"}},
+		},
+	}
+
+	// Files that fit are reviewed whole
+	whole := reviewChunks(file, 1000)
+	if len(whole) != 1 || strings.Count(whole[0], "@@ -") != 2 ||
+		!strings.HasPrefix(whole[0], file.Header+"\n") {
+		t.Errorf("chunks = %q, want the whole file", whole)
+	}
+	if !strings.Contains(whole[0], "    1 +b") || !strings.Contains(whole[0], "    9 +d") {
+		t.Errorf("chunk = %q, want numbered lines", whole[0])
+	}
+
+	// Larger files are reviewed per hunk, each with the file header
+	hunks := reviewChunks(file, len(whole[0])-1)
+	if len(hunks) != 2 {
+		t.Fatalf("chunks = %q, want one per hunk", hunks)
+	}
+	for i, chunk := range hunks {
+		if !strings.HasPrefix(chunk, file.Header+"\n"+file.Hunks[i].Header) {
+			t.Errorf("chunk %d = %q, want the header and hunk %d", i, chunk, i)
+		}
+	}
+
+	if chunks := reviewChunks(git.FileDiff{Path: "logo.png"}, 1000); chunks != nil {
+		t.Errorf("chunks = %q, want none without hunks", chunks)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	data, err := sarifLog([]Finding{
+		{File: "main.go", Line: 12, Severity: "error", Message: "nil map", Suggestion: "make it"},
+		{File: "go.mod", Line: 0, Severity: "note", Message: "old Go"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool    struct{ Driver struct{ Name string } }
+			Results []struct {
+				RuleID    string
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine int }
+					}
+				}
+				Properties map[string]string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "gptx" {
+		t.Fatalf("log = %s, want a SARIF 2.1.0 run of gptx", data)
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	first := results[0]
+	location := first.Locations[0].PhysicalLocation
+	if first.Level != "error" || first.Message.Text != "nil map" || first.RuleID != "gptx-review" ||
+		location.ArtifactLocation.URI != "main.go" || location.Region.StartLine != 12 ||
+		first.Properties["suggestion"] != "make it" {
+		t.Errorf("result = %+v, want the first finding", first)
+	}
+	// SARIF lines start at 1, and results without suggestions have none
+	if line := results[1].Locations[0].PhysicalLocation.Region.StartLine; line != 1 {
+		t.Errorf("start line = %d, want 1", line)
+	}
+	if results[1].Properties != nil {
+		t.Errorf("properties = %v, want none", results[1].Properties)
+	}
+
+	// No findings are an empty list of results, not null
+	data, err = sarifLog(nil)
+	if err != nil || !strings.Contains(string(data), `"results": []`) {
+		t.Errorf("sarifLog(nil) = %s, %v, want empty results", data, err)
+	}
+}
+
+// TestReviewStaged reviews the staged changes with the global --staged,
+// leaving unstaged changes out.
+func TestReviewStaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitRun := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", args[0], err, out)
+		}
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitRun("init", "-q")
+	write("staged.go", "package a\n")
+	write("unstaged.go", "package a\n")
+	gitRun("add", ".")
+	gitRun("commit", "-q", "-m", "init")
+	write("staged.go", "package a\n\nvar x = 1\n")
+	write("unstaged.go", "package a\n\nvar y = 2\n")
+	gitRun("add", "staged.go")
+
+	// The script only accepts a review of the staged file
+	findings := `{"findings": [{"file": "staged.go", "line": 3, "severity": "note", "message": "unused", "suggestion": ""}]}`
+	script, _ := json.Marshal(map[string]any{"turns": []any{map[string]any{
+		"expect": map[string]any{"contains": []string{"staged.go"}},
+		"events": []any{map[string]any{"text": findings}},
+	}}})
+	write("script.json", string(script))
+
+	stdout, _ := runCLIIn(t, dir, "review", "--staged", "--provider", "fake", "--script", "script.json")
+	if want := "staged.go:3: note: unused"; !strings.Contains(stdout, want) {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"golang.org/x/term"
+)
+
+// status is the live status line of the model's activity. It is shown on
+// stderr while nothing else is printed, and only on terminals.
+var status = &statusLine{
+	enabled: term.IsTerminal(int(os.Stderr.Fd())) && os.Getenv("TERM") != "dumb",
+	atStart: true,
+}
+
+// statusLine is a transient line of stderr that output replaces.
+type statusLine struct {
+	enabled bool           // Stderr is a terminal
+	shown   bool           // The line is on screen
+	atStart bool           // Output ended at the start of a line
+	args    map[string]int // Bytes of arguments streamed by tool call
+	mu      sync.Mutex
+}
+
+// HandleEvent shows what the model is doing: thinking until it replies,
+// and the tool it is calling while it writes the call's arguments.
+func (s *statusLine) HandleEvent(event events.Event) {
+	switch e := event.(type) {
+	case events.TurnStarted:
+		s.show("thinking…")
+	case events.ToolCallDelta:
+		s.mu.Lock()
+		if s.args == nil {
+			s.args = map[string]int{}
+		}
+		s.args[e.ID] += len(e.Args)
+		size := s.args[e.ID]
+		s.mu.Unlock()
+		s.show("calling %s… (%d bytes)", e.Name, size)
+	case events.ToolCallStarted:
+		s.show("running %s…", e.Name)
+	case events.ToolCallFinished:
+		s.show("thinking…")
+	case events.WebSearch:
+		s.show("searching the web…")
+	case events.TurnFinished:
+		s.clear()
+		s.mu.Lock()
+		s.args = nil
+		s.mu.Unlock()
+	}
+}
+
+// show replaces the status line, if output is at the start of a line.
+func (s *statusLine) show(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled || silent || !s.atStart {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	fmt.Fprintf(os.Stderr, "\r\033[K%s%s%s", Dim, text, Reset)
+	s.shown = true
+}
+
+// clear removes the status line, so that output is printed in its place.
+func (s *statusLine) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		s.shown = false
+	}
+}
+
+// printed clears the status line before output is printed and records
+// whether the output ends a line. Output to a stream that isn't a terminal
+// doesn't move the cursor.
+func (s *statusLine) printed(file *os.File, text string) {
+	if file == os.Stdout && !isTerm || text == "" {
+		return
+	}
+	s.clear()
+	s.mu.Lock()
+	s.atStart = strings.HasSuffix(text, "\n")
+	s.mu.Unlock()
+}
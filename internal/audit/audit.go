@@ -0,0 +1,286 @@
+// Package audit keeps an append-only log of what models ran: every tool
+// call, with its arguments, directory, approval decision, exit status,
+// duration and output hash, and optionally the transcripts of requests.
+// Records are JSON lines, with secrets redacted, in files rotated by size.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+// Record types.
+const (
+	ToolRecord     = "tool"     // A tool call
+	RequestRecord  = "request"  // The messages sent to a model
+	ResponseRecord = "response" // The messages a model replied with
+)
+
+// Decisions on tool calls.
+const (
+	Allowed   = "allowed"   // The call ran as requested
+	Rewritten = "rewritten" // The call ran with rewritten arguments
+	Rejected  = "rejected"  // The call was rejected and did not run
+)
+
+// Redacted replaces secrets in records.
+const Redacted = "[REDACTED]"
+
+// Record is an entry of the audit log. Fields are set by record type.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Session string    `json:"session"` // ID of the gptx run
+	Project string    `json:"project"` // Repository root, or the directory
+	Dir     string    `json:"cwd"`     // Working directory
+	Model   string    `json:"model,omitempty"`
+
+	// Tool calls
+	Tool       string `json:"tool,omitempty"`
+	CallID     string `json:"call_id,omitempty"`
+	Args       string `json:"args,omitempty"`
+	Requested  string `json:"requested_args,omitempty"` // Arguments before a rewrite
+	Decision   string `json:"decision,omitempty"`
+	Exit       *int   `json:"exit_status,omitempty"` // Set if the call ran to an exit
+	Duration   int64  `json:"duration_ms,omitempty"`
+	OutputHash string `json:"output_sha256,omitempty"`
+	OutputSize int    `json:"output_bytes,omitempty"`
+
+	// Transcripts
+	Messages []Message     `json:"messages,omitempty"`
+	Usage    *events.Usage `json:"usage,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Message is a message of a transcript.
+type Message struct {
+	Role    string   `json:"role"`
+	Name    string   `json:"name,omitempty"`
+	Content string   `json:"content,omitempty"`
+	CallID  string   `json:"call_id,omitempty"`
+	Call    string   `json:"tool_call,omitempty"` // Tool name and arguments
+	Files   []string `json:"files,omitempty"`
+	Context []string `json:"context,omitempty"` // Attached context, with its label
+}
+
+// secretPatterns match common credentials, which are always redacted.
+var secretPatterns = []string{
+	`sk-[A-Za-z0-9_-]{16,}`,                 // OpenAI and similar API keys
+	`(?i)bearer\s+[A-Za-z0-9._~+/-]{16,}=*`, // Bearer tokens
+	`AKIA[0-9A-Z]{16}`,                      // AWS access keys
+	`gh[pousr]_[A-Za-z0-9]{36,}`,            // GitHub tokens
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// MARK: Log
+// ============================================================================
+
+// Log is an audit log in a directory. The current file is rotated once it
+// exceeds the size limit, keeping a number of rotated files.
+type Log struct {
+	dir      string           // Directory of the log files
+	size     int64            // Size limit of a file, 0 for none
+	keep     int              // Rotated files to keep, 0 for all
+	secrets  []string         // Literal values to redact
+	patterns []*regexp.Regexp // Patterns of values to redact
+}
+
+// NewLog creates an audit log in a directory, with a size limit of a file
+// in bytes and the number of rotated files to keep. Secrets and matches of
+// the patterns, and of common credential formats, are redacted.
+func NewLog(dir string, size int64, keep int, secrets, patterns []string) (*Log, error) {
+	log := &Log{dir: dir, size: size, keep: keep}
+	for _, secret := range secrets {
+		if secret != "" {
+			log.secrets = append(log.secrets, secret)
+		}
+	}
+
+	var errs []error
+	for _, pattern := range append(slices.Clone(secretPatterns), patterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("audit: scrub pattern %q: %w", pattern, err))
+			continue
+		}
+		log.patterns = append(log.patterns, re)
+	}
+	return log, errors.Join(errs...)
+}
+
+// path is the current file of the log.
+func (l *Log) path() string {
+	return filepath.Join(l.dir, "audit.jsonl")
+}
+
+// Write appends a record to the log, with its secrets redacted.
+func (l *Log) Write(record Record) error {
+	data, err := json.Marshal(l.redact(record))
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if err := l.rotate(); err != nil {
+		return fmt.Errorf("audit: rotate: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+// rotate renames the current file once it exceeds the size limit and
+// deletes the oldest rotated files beyond those kept. Files already rotated
+// by another process are left to it.
+func (l *Log) rotate() error {
+	info, err := os.Stat(l.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if l.size <= 0 || info.Size() < l.size {
+		return nil
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405.000000000")
+	err = os.Rename(l.path(), filepath.Join(l.dir, "audit-"+stamp+".jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if l.keep <= 0 {
+		return nil
+	}
+
+	rotated, err := l.rotated()
+	if err != nil {
+		return err
+	}
+	for len(rotated) > l.keep {
+		if err := os.Remove(rotated[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// rotated returns the rotated files of the log, oldest first.
+func (l *Log) rotated() ([]string, error) {
+	rotated, err := filepath.Glob(filepath.Join(l.dir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(rotated) // Timestamps sort in time order
+	return rotated, nil
+}
+
+// redact returns the record with its secrets redacted.
+func (l *Log) redact(record Record) Record {
+	redact := func(text string) string {
+		for _, secret := range l.secrets {
+			text = strings.ReplaceAll(text, secret, Redacted)
+		}
+		for _, re := range l.patterns {
+			text = re.ReplaceAllString(text, Redacted)
+		}
+		return text
+	}
+
+	record.Args = redact(record.Args)
+	record.Requested = redact(record.Requested)
+	record.Error = redact(record.Error)
+	messages := make([]Message, len(record.Messages))
+	for i, msg := range record.Messages {
+		msg.Content = redact(msg.Content)
+		msg.Call = redact(msg.Call)
+		context := make([]string, len(msg.Context))
+		for j, attached := range msg.Context {
+			context[j] = redact(attached)
+		}
+		msg.Context = context
+		messages[i] = msg
+	}
+	if record.Messages != nil {
+		record.Messages = messages
+	}
+	return record
+}
+
+// MARK: Queries
+// ============================================================================
+
+// Filter selects records of the log. Empty fields match every record.
+type Filter struct {
+	Since, Until time.Time // Time range
+	Type         string    // Record type
+	Tool         string    // Tool name
+	Project      string    // Project directory
+}
+
+// matches reports whether a record is selected by the filter.
+func (f Filter) matches(record Record) bool {
+	return (f.Since.IsZero() || !record.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || record.Time.Before(f.Until)) &&
+		(f.Type == "" || record.Type == f.Type) &&
+		(f.Tool == "" || record.Tool == f.Tool) &&
+		(f.Project == "" || record.Project == f.Project)
+}
+
+// Query returns the records selected by the filter, oldest first.
+func (l *Log) Query(filter Filter) ([]Record, error) {
+	paths, err := l.rotated()
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	paths = append(paths, l.path())
+
+	var records []Record
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 64*1024*1024) // Transcripts can be long
+		for line := 1; scanner.Scan(); line++ {
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("audit: %s:%d: %w", path, line, err)
+			}
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("audit: %s: %w", path, err)
+		}
+	}
+	return records, nil
+}
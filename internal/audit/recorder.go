@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/git"
+	"github.com/mohdfareed/gptx-cli/internal/hooks"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// Recorder records a model's tool calls, and optionally its transcripts,
+// in an audit log. It is an events.Handler, its ToolHook method is a
+// gptx.ToolHook and its Transcripts method is a gptx.Middleware.
+type Recorder struct {
+	log     *Log
+	session string                           // ID of the gptx run
+	project string                           // Repository root, or the directory
+	logf    func(format string, args ...any) // Reports failed writes
+
+	model     string            // Model of the running request
+	requested map[string]string // Arguments requested by the model, by call ID
+	args      map[string]string // Arguments of running tool calls, by call ID
+	mu        sync.Mutex
+}
+
+// NewRecorder creates a recorder of a gptx run into a log. Failed writes
+// are reported with logf.
+func NewRecorder(ctx context.Context, log *Log, logf func(format string, args ...any)) *Recorder {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &Recorder{
+		log: log, session: hex.EncodeToString(id), project: project(ctx), logf: logf,
+		requested: map[string]string{}, args: map[string]string{},
+	}
+}
+
+// ToolHook records the arguments the model requested for a tool call,
+// before other hooks can rewrite them. It must be the first tool hook.
+func (r *Recorder) ToolHook(_ context.Context, call gptx.ToolCall) (gptx.ToolCall, error) {
+	r.mu.Lock()
+	r.requested[call.ID] = call.Arguments
+	r.mu.Unlock()
+	return call, nil
+}
+
+// HandleEvent records finished tool calls.
+func (r *Recorder) HandleEvent(event events.Event) {
+	r.mu.Lock()
+	switch e := event.(type) {
+	case events.TurnStarted:
+		r.model = e.Config.Model
+		r.mu.Unlock()
+	case events.ToolCallStarted:
+		r.args[e.ID] = e.Args
+		r.mu.Unlock()
+	case events.ToolCallFinished:
+		record := r.record(ToolRecord)
+		record.Model, record.Tool, record.CallID = r.model, e.Name, e.ID
+		record.Args = r.args[e.ID]
+		requested := r.requested[e.ID]
+		delete(r.args, e.ID)
+		delete(r.requested, e.ID)
+		r.mu.Unlock()
+
+		record.Decision = Allowed
+		switch {
+		case errors.Is(e.Err, hooks.ErrRejected):
+			record.Decision = Rejected
+		case requested != "" && requested != record.Args:
+			record.Decision, record.Requested = Rewritten, requested
+		}
+		record.Duration = e.Duration.Milliseconds()
+
+		// Calls exit with 0 on success, or with the status of their command
+		var exitErr *exec.ExitError
+		if e.Err == nil {
+			exit, sum := 0, sha256.Sum256([]byte(e.Result))
+			record.Exit = &exit
+			record.OutputHash, record.OutputSize = hex.EncodeToString(sum[:]), len(e.Result)
+		} else {
+			record.Error = e.Err.Error()
+			if errors.As(e.Err, &exitErr) {
+				exit := exitErr.ExitCode()
+				record.Exit = &exit
+			}
+		}
+		r.write(record)
+	default:
+		r.mu.Unlock()
+	}
+}
+
+// Transcripts is a middleware that records the messages of each request
+// and response, including requests answered by other middlewares.
+func (r *Recorder) Transcripts(client gptx.Client) gptx.Client {
+	return gptx.ClientFunc(func(ctx context.Context, request gptx.Request) (gptx.Response, error) {
+		record := r.record(RequestRecord)
+		record.Model = request.Config.Model
+		record.Messages = append([]Message{{Role: "system", Content: request.Config.SysPrompt}},
+			transcript(request.Messages)...)
+		r.write(record)
+
+		response, err := client.SendRequest(ctx, request)
+		record = r.record(ResponseRecord)
+		record.Model = request.Config.Model
+		record.Messages = transcript(response.Messages)
+		if response.Usage != "" {
+			usage := events.ParseUsage(response.Usage)
+			record.Usage = &usage
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		r.write(record)
+		return response, err
+	})
+}
+
+// record returns a new record of the run.
+func (r *Recorder) record(kind string) Record {
+	dir, _ := os.Getwd()
+	return Record{
+		Time: time.Now().UTC(), Type: kind, Session: r.session,
+		Project: r.project, Dir: dir,
+	}
+}
+
+// write writes a record, reporting failures.
+func (r *Recorder) write(record Record) {
+	if err := r.log.Write(record); err != nil {
+		r.logf("Audit log: %s", err)
+	}
+}
+
+// transcript returns the messages of a transcript.
+func transcript(messages []gptx.Message) []Message {
+	var result []Message
+	for _, msg := range messages {
+		entry := Message{
+			Role: msg.Role, Name: msg.Name, Content: msg.Content,
+			CallID: msg.CallID, Files: msg.Files,
+		}
+		if msg.ToolCall != nil {
+			entry.CallID = msg.ToolCall.ID
+			entry.Call = msg.ToolCall.Name + msg.ToolCall.Arguments
+		}
+		for _, attached := range msg.Context {
+			entry.Context = append(entry.Context, attached.Label+"\n"+attached.Content)
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// project returns the root of the current repository, or the current
+// directory outside a repository.
+func project(ctx context.Context) string {
+	if root, err := git.Run(ctx, "rev-parse", "--show-toplevel"); err == nil {
+		return strings.TrimSpace(root)
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
@@ -0,0 +1,114 @@
+// Package auth resolves API keys from config, credential helper commands,
+// the OS keyring and provider environment variables.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Providers maps the providers that use API keys to their standard API key
+// variables.
+var Providers = map[string]string{
+	"openai": "OPENAI_API_KEY",
+}
+
+// ErrNoKey is returned when no API key is found for a provider.
+var ErrNoKey = errors.New("API key not set")
+
+// Key sources reported by Lookup.
+const (
+	SourceConfig  = "config"  // --key flag, GPTX_API_KEY or config files
+	SourceCommand = "command" // GPTX_API_KEY_CMD
+	SourceKeyring = "keyring" // OS keyring
+)
+
+// Account returns the keyring account of a provider's key: the provider,
+// or the provider and the host of a custom API base URL, so that keys of
+// other endpoints are stored apart from the provider's.
+func Account(provider, baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return provider + "@" + u.Host
+	}
+	return provider
+}
+
+// Lookup returns a provider's API key and its source, trying in order:
+// the configured key, the key command, the OS keyring entry of the
+// provider's account, and the provider's standard environment variable
+// (e.g. OPENAI_API_KEY).
+func Lookup(
+	ctx context.Context, provider, baseURL, key, command string,
+) (string, string, error) {
+	if key != "" {
+		return key, SourceConfig, nil
+	}
+
+	if command != "" {
+		key, err := RunCommand(ctx, command)
+		if err != nil {
+			return "", "", err
+		}
+		return key, SourceCommand, nil
+	}
+
+	if keyring, err := DefaultKeyring(); err == nil {
+		key, err := keyring.Get(Account(provider, baseURL))
+		if err == nil {
+			return key, SourceKeyring, nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return "", "", err
+		}
+	}
+
+	if env := Providers[provider]; env != "" && os.Getenv(env) != "" {
+		return os.Getenv(env), env, nil
+	}
+	return "", "", fmt.Errorf("%s: %w", provider, ErrNoKey)
+}
+
+// MARK: Key Commands
+// ============================================================================
+
+// commandKeys caches the output of key commands for the process.
+var commandKeys = struct {
+	sync.Mutex
+	keys map[string]string
+}{keys: map[string]string{}}
+
+// RunCommand runs a credential helper command, such as "pass show openai",
+// and returns the first line of its output. Results are cached, so each
+// command runs at most once per process.
+func RunCommand(ctx context.Context, command string) (string, error) {
+	commandKeys.Lock()
+	defer commandKeys.Unlock()
+	if key, ok := commandKeys.keys[command]; ok {
+		return key, nil
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("key command: %w", err)
+	}
+
+	key, _, _ := strings.Cut(string(output), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("key command: no output")
+	}
+	commandKeys.keys[command] = key
+	return key, nil
+}
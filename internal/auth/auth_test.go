@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestAccount(t *testing.T) {
+	tests := []struct {
+		provider, baseURL, want string
+	}{
+		{"openai", "", "openai"},
+		{"openai", "https://api.example.com/v1", "openai@api.example.com"},
+		{"openai", "http://localhost:8080/v1/", "openai@localhost:8080"},
+		{"openai", "not a url", "openai"},
+	}
+	for _, test := range tests {
+		if got := Account(test.provider, test.baseURL); got != test.want {
+			t.Errorf("Account(%q, %q) = %q, want %q", test.provider, test.baseURL, got, test.want)
+		}
+	}
+}
+
+func TestLookupOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("key commands in these tests are POSIX shell commands")
+	}
+	ctx := context.Background()
+	key, source, err := Lookup(ctx, "openai", "", "sk-config", "echo sk-command")
+	if err != nil || key != "sk-config" || source != SourceConfig {
+		t.Errorf("Lookup() = %q, %q, %v, want the configured key", key, source, err)
+	}
+
+	key, source, err = Lookup(ctx, "openai", "", "", "printf 'sk-command\\nextra'")
+	if err != nil || key != "sk-command" || source != SourceCommand {
+		t.Errorf("Lookup() = %q, %q, %v, want the first line of the command", key, source, err)
+	}
+
+	if _, _, err := Lookup(ctx, "openai", "", "", "exit 3"); err == nil {
+		t.Error("Lookup() succeeded with a failing key command")
+	}
+}
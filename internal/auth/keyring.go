@@ -0,0 +1,177 @@
+// Package auth resolves API keys from config, credential helper commands,
+// the OS keyring and provider environment variables.
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service names the app's entries in the OS keyring.
+const service = "gptx"
+
+// ErrNotFound is returned when a keyring has no key for a provider.
+var ErrNotFound = errors.New("key not found in keyring")
+
+// ErrNoKeyring is returned when no keyring is available on the system.
+var ErrNoKeyring = errors.New("no keyring available")
+
+// Keyring stores API keys per provider.
+type Keyring interface {
+	Get(provider string) (string, error)
+	Set(provider, key string) error
+	Delete(provider string) error
+}
+
+// DefaultKeyring returns the system keyring: the Secret Service on Linux
+// (through secret-tool) or the login keychain on macOS (through security).
+func DefaultKeyring() (Keyring, error) {
+	var keyring Keyring
+	var tool string
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		keyring, tool = secretService{}, "secret-tool"
+	case "darwin":
+		keyring, tool = keychain{}, "security"
+	default:
+		return nil, ErrNoKeyring
+	}
+
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, fmt.Errorf("%w: %s not found", ErrNoKeyring, tool)
+	}
+	return keyring, nil
+}
+
+// MARK: Secret Service
+// ============================================================================
+
+// secretService is the freedesktop Secret Service (GNOME Keyring, KWallet).
+type secretService struct{}
+
+func (secretService) Get(provider string) (string, error) {
+	out, err := run(nil, "secret-tool", "lookup", "service", service, "account", provider)
+	var toolErr *toolError
+	if out == "" && (err == nil || errors.As(err, &toolErr) && toolErr.code() == 1 && toolErr.stderr == "") {
+		return "", ErrNotFound // lookup fails silently if not found
+	}
+	return out, err
+}
+
+func (secretService) Set(provider, key string) error {
+	label := service + " " + provider + " API key"
+	_, err := run(strings.NewReader(key), "secret-tool", "store",
+		"--label", label, "service", service, "account", provider)
+	return err
+}
+
+func (s secretService) Delete(provider string) error {
+	if _, err := s.Get(provider); err != nil {
+		return err
+	}
+	_, err := run(nil, "secret-tool", "clear", "service", service, "account", provider)
+	return err
+}
+
+// MARK: Keychain
+// ============================================================================
+
+// keychain is the macOS login keychain.
+type keychain struct{}
+
+// errItemNotFound is the exit status of security for missing items.
+const errItemNotFound = 44
+
+func (keychain) Get(provider string) (string, error) {
+	out, err := run(nil, "security", "find-generic-password", "-s", service, "-a", provider, "-w")
+	if exitCode(err) == errItemNotFound {
+		return "", ErrNotFound
+	}
+	return out, err
+}
+
+// Set runs the command through security's interactive mode, so that the
+// key is read from stdin instead of appearing in the process arguments.
+func (keychain) Set(provider, key string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quote(service), quote(provider), quote(key))
+	_, err := run(strings.NewReader(command), "security", "-i")
+	return err
+}
+
+func (keychain) Delete(provider string) error {
+	_, err := run(nil, "security", "delete-generic-password", "-s", service, "-a", provider)
+	if exitCode(err) == errItemNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// quote quotes an argument of a security interactive command.
+func quote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// run runs a keyring tool and returns its trimmed output. It fails with a
+// toolError if the tool fails or reports an error.
+func run(stdin *strings.Reader, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	msg := strings.TrimSpace(stderr.String())
+	interactive := len(args) > 0 && args[0] == "-i" // security exits with 0 on errors
+	if err != nil || interactive && msg != "" {
+		return "", &toolError{name: name, err: err, stderr: msg}
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// toolError is a failure of a keyring tool.
+type toolError struct {
+	name   string
+	err    error // Exit error, nil if the tool only reported an error
+	stderr string
+}
+
+func (e *toolError) Error() string {
+	switch {
+	case e.err == nil:
+		return e.name + ": " + e.stderr
+	case e.stderr == "":
+		return e.name + ": " + e.err.Error()
+	}
+	return e.name + ": " + e.err.Error() + ": " + e.stderr
+}
+
+func (e *toolError) Unwrap() error { return e.err }
+
+// code returns the exit status of the tool, or -1 if it didn't exit.
+func (e *toolError) code() int {
+	var exitErr *exec.ExitError
+	if errors.As(e.err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// exitCode returns the exit status of a failed tool, or -1 if it didn't
+// run to an exit.
+func exitCode(err error) int {
+	var toolErr *toolError
+	if errors.As(err, &toolErr) {
+		return toolErr.code()
+	}
+	return -1
+}
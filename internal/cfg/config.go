@@ -4,9 +4,13 @@ package cfg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
@@ -17,17 +21,61 @@ You are '%s', a CLI app. You are an extension of the command line.
 You behave and respond like a command line tool. Be concise.
 `
 
+// Providers are the supported model providers. The fake provider replies
+// from a script, for demos and tests.
+var Providers = []string{"openai", "fake"}
+
 // Config stores application configuration settings.
 type Config struct {
-	APIKey    string   // OpenAI API key
+	Profile   string   // Configuration profile
+	Provider  string   // Model provider
+	APIKey    string   // Provider API key
+	KeyCmd    string   // Command that prints the API key
+	BaseURL   string   // API base URL
 	Model     string   // Model name
+	Fallback  []string // Fallback models ([provider/]model), in order
+	Route     bool     // Route requests to the cheapest capable model
 	SysPrompt string   // System prompt
+	Additions []string // Per-run additions to the system prompt
 	Files     []string // Attached files
 	WebSearch bool     // Enable web search
 	Shell     string   // Shell command
-	Reason    bool     // Enable reasoning
+	Reason    string   // Reasoning effort: low, medium or high
+	Summary   string   // Reasoning summary: auto, concise, detailed or none
 	Tokens    int      // Max tokens
 	Temp      float64  // Temperature (controls randomness)
+	Uploads   int      // Upload files larger than this size (KB)
+	Retries   int      // Retries of failed requests
+	Script    string   // Script of the fake provider
+	Hooks     []string // Commands run on model events (event=command)
+
+	// Telemetry
+	OTLPEndpoint  string   // Export telemetry to this OTLP/HTTP collector
+	OTLPHeaders   []string // Headers of OTLP requests (key=value)
+	TelemetryFile string   // Export telemetry to this JSON lines file
+
+	// HTTP cassettes
+	Record string   // Record API exchanges into this cassette
+	Replay string   // Replay API exchanges from this cassette
+	Scrub  []string // Patterns scrubbed from cassettes and audit logs
+
+	// Audit log
+	Audit            bool // Record tool calls in the audit log
+	AuditTranscripts bool // Record request and response transcripts
+	AuditSize        int  // Size of an audit log file before it rotates (MB)
+	AuditKeep        int  // Rotated audit log files to keep
+
+	// Response cache
+	Cache     bool          // Replay responses to identical requests
+	CacheTTL  time.Duration // Lifetime of cached responses
+	CacheSize int           // Cache size limit (MB)
+
+	// Git context
+	Diff    bool     // Attach the working tree diff
+	Staged  bool     // Attach the staged diff
+	DiffRef string   // Attach the diff against a ref
+	Log     int      // Attach this many recent commits
+	Blame   []string // Attach the blame of files or line ranges
 }
 
 // MARK: Flags
@@ -36,11 +84,37 @@ type Config struct {
 // Flags returns the CLI flags for the model configuration.
 func (c *Config) Flags() []cli.Flag {
 	return []cli.Flag{
+		&cli.StringFlag{
+			Name: "profile", Usage: "Select configuration profile",
+			Category: "config", Destination: &c.Profile,
+			Sources: cli.EnvVars(ProfileEnvVar),
+		},
+		&cli.StringFlag{
+			Name: "provider", Usage: "Select model provider (openai, fake)",
+			Category: "config", Destination: &c.Provider,
+			Sources: cli.EnvVars(EnvVarPrefix + "PROVIDER"),
+			Value:   "openai",
+			Validator: func(provider string) error {
+				if !slices.Contains(Providers, provider) {
+					return fmt.Errorf("unknown provider: %s", provider)
+				}
+				return nil
+			},
+		},
 		&cli.StringFlag{
 			Name: "key", Usage: "Set Platform API key",
 			Category: "config", Destination: &c.APIKey,
-			Sources:  cli.EnvVars(EnvVarPrefix + "API_KEY"),
-			Required: true,
+			Sources: cli.EnvVars(EnvVarPrefix + "API_KEY"),
+		},
+		&cli.StringFlag{
+			Name: "key-cmd", Usage: "Run a command that prints the API key",
+			Category: "config", Destination: &c.KeyCmd,
+			Sources: cli.EnvVars(EnvVarPrefix + "API_KEY_CMD"),
+		},
+		&cli.StringFlag{
+			Name: "base-url", Usage: "Set Platform API base URL",
+			Category: "config", Destination: &c.BaseURL,
+			Sources: cli.EnvVars(EnvVarPrefix + "BASE_URL"),
 		},
 		&cli.StringFlag{
 			Name: "model", Usage: "Select model to use",
@@ -48,14 +122,31 @@ func (c *Config) Flags() []cli.Flag {
 			Sources: cli.EnvVars(EnvVarPrefix + "MODEL"),
 			Value:   "o4-mini",
 		},
-		// CONFIG
+		&cli.StringSliceFlag{
+			Name: "fallback", Usage: "Fall back to these models on errors ([provider/]model)",
+			Category: "config", Destination: &c.Fallback,
+			Sources: cli.EnvVars(EnvVarPrefix + "FALLBACK"),
+			Value:   []string{},
+		},
 		&cli.BoolFlag{
-			Name: "reason", Usage: "Allow the model to reason",
-			Category: "config", Destination: &c.Reason,
-			Sources:     cli.EnvVars(EnvVarPrefix + "REASON"),
+			Name: "route", Usage: "Send requests to the cheapest capable model",
+			Category: "config", Destination: &c.Route,
+			Sources:     cli.EnvVars(EnvVarPrefix + "ROUTE"),
 			HideDefault: true,
 		},
 		// CONFIG
+		&cli.StringFlag{
+			Name: "reason", Usage: "Set reasoning effort (low, medium, high)",
+			Category: "config", Destination: &c.Reason,
+			Sources: cli.EnvVars(EnvVarPrefix + "REASON"),
+		},
+		&cli.StringFlag{
+			Name: "reasoning-summary", Usage: "Set reasoning summary (auto, concise, detailed, none)",
+			Category: "config", Destination: &c.Summary,
+			Sources: cli.EnvVars(EnvVarPrefix + "REASONING_SUMMARY"),
+			Value:   "auto",
+		},
+		// CONFIG
 		&cli.IntFlag{
 			Name: "max", Usage: "Limit response length",
 			Category: "config", Destination: &c.Tokens,
@@ -63,7 +154,7 @@ func (c *Config) Flags() []cli.Flag {
 			HideDefault: true,
 		},
 		&cli.Float64Flag{
-			Name: "temp", Usage: "Set response randomness (0-100)",
+			Name: "temp", Usage: "Set response randomness (0-2)",
 			Category: "config", Destination: &c.Temp,
 			Sources: cli.EnvVars(EnvVarPrefix + "TEMP"),
 			Value:   1,
@@ -76,6 +167,12 @@ func (c *Config) Flags() []cli.Flag {
 			Value:   fmt.Sprintf(SYS_PROMPT, AppName), Aliases: []string{"s"},
 			TakesFile: true, Action: c.resolveSysPrompt, HideDefault: true,
 		},
+		&cli.StringSliceFlag{
+			Name: "add-prompt", Usage: "Add instructions to the system prompt",
+			Category: "config", Destination: &c.Additions,
+			Sources: cli.EnvVars(EnvVarPrefix + "ADD_PROMPT"),
+			Value:   []string{}, TakesFile: true, Action: c.resolveAdditions,
+		},
 		&cli.StringSliceFlag{
 			Name: "files", Usage: "Attach files to the message",
 			Category: "context", Destination: &c.Files,
@@ -83,6 +180,135 @@ func (c *Config) Flags() []cli.Flag {
 			Value:   []string{}, Aliases: []string{"f"},
 			TakesFile: true, Action: c.resolveFiles,
 		},
+		&cli.IntFlag{
+			Name: "upload-size", Usage: "Upload attachments larger than this (KB, 0 to disable)",
+			Category: "context", Destination: &c.Uploads,
+			Sources: cli.EnvVars(EnvVarPrefix + "UPLOAD_SIZE"),
+			Value:   512,
+		},
+		&cli.IntFlag{
+			Name: "retries", Usage: "Retry rate-limited and failed requests",
+			Category: "config", Destination: &c.Retries,
+			Sources: cli.EnvVars(EnvVarPrefix + "RETRIES"),
+			Value:   3,
+		},
+		&cli.StringFlag{
+			Name: "script", Usage: "Reply from a script file (fake provider)",
+			Category: "config", Destination: &c.Script,
+			Sources: cli.EnvVars(EnvVarPrefix + "SCRIPT"), TakesFile: true,
+		},
+		&LinesFlag{
+			Name: "hook", Usage: "Run a command on model events (event=command)",
+			Category: "config", Destination: &c.Hooks,
+			Sources: cli.EnvVars(EnvVarPrefix + "HOOKS"),
+			Value:   []string{},
+		},
+		// TELEMETRY
+		&cli.StringFlag{
+			Name: "otlp-endpoint", Usage: "Export traces and metrics to an OTLP/HTTP collector",
+			Category: "config", Destination: &c.OTLPEndpoint,
+			Sources: cli.EnvVars(EnvVarPrefix + "OTLP_ENDPOINT"),
+		},
+		&cli.StringSliceFlag{
+			Name: "otlp-header", Usage: "Send a header with OTLP exports (key=value)",
+			Category: "config", Destination: &c.OTLPHeaders,
+			Sources: cli.EnvVars(EnvVarPrefix + "OTLP_HEADERS"),
+			Value:   []string{},
+		},
+		&cli.StringFlag{
+			Name: "telemetry-file", Usage: "Export traces and metrics to a JSON lines file",
+			Category: "config", Destination: &c.TelemetryFile,
+			Sources: cli.EnvVars(EnvVarPrefix + "TELEMETRY_FILE"), TakesFile: true,
+		},
+		// CASSETTES
+		&cli.StringFlag{
+			Name: "record", Usage: "Record API exchanges into a cassette file",
+			Category: "config", Destination: &c.Record,
+			Sources: cli.EnvVars(EnvVarPrefix + "RECORD"), TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name: "replay", Usage: "Replay API exchanges from a cassette file",
+			Category: "config", Destination: &c.Replay,
+			Sources: cli.EnvVars(EnvVarPrefix + "REPLAY"), TakesFile: true,
+		},
+		&cli.StringSliceFlag{
+			Name: "scrub", Usage: "Scrub matches of regular expressions from cassettes and audit logs",
+			Category: "config", Destination: &c.Scrub,
+			Sources: cli.EnvVars(EnvVarPrefix + "SCRUB"),
+			Value:   []string{},
+		},
+		// AUDIT
+		&cli.BoolWithInverseFlag{
+			Name: "audit", Usage: "Record tool calls in the audit log",
+			Category: "config", Destination: &c.Audit,
+			Sources: cli.EnvVars(EnvVarPrefix + "AUDIT"),
+		},
+		&cli.BoolFlag{
+			Name: "audit-transcripts", Usage: "Record request and response transcripts in the audit log",
+			Category: "config", Destination: &c.AuditTranscripts,
+			Sources:     cli.EnvVars(EnvVarPrefix + "AUDIT_TRANSCRIPTS"),
+			HideDefault: true,
+		},
+		&cli.IntFlag{
+			Name: "audit-size", Usage: "Rotate the audit log at this size (MB, 0 for no limit)",
+			Category: "config", Destination: &c.AuditSize,
+			Sources: cli.EnvVars(EnvVarPrefix + "AUDIT_SIZE"),
+			Value:   10,
+		},
+		&cli.IntFlag{
+			Name: "audit-keep", Usage: "Keep this many rotated audit logs (0 to keep all)",
+			Category: "config", Destination: &c.AuditKeep,
+			Sources: cli.EnvVars(EnvVarPrefix + "AUDIT_KEEP"),
+			Value:   10,
+		},
+		// CACHE
+		&cli.BoolWithInverseFlag{
+			Name: "cache", Usage: "Replay cached responses to identical requests",
+			Category: "config", Destination: &c.Cache,
+			Sources: cli.EnvVars(EnvVarPrefix + "CACHE"),
+		},
+		&cli.DurationFlag{
+			Name: "cache-ttl", Usage: "Expire cached responses after this duration (0 to keep)",
+			Category: "config", Destination: &c.CacheTTL,
+			Sources: cli.EnvVars(EnvVarPrefix + "CACHE_TTL"),
+			Value:   24 * time.Hour,
+		},
+		&cli.IntFlag{
+			Name: "cache-size", Usage: "Limit the response cache size (MB, 0 for no limit)",
+			Category: "config", Destination: &c.CacheSize,
+			Sources: cli.EnvVars(EnvVarPrefix + "CACHE_SIZE"),
+			Value:   100,
+		},
+		// GIT CONTEXT
+		&cli.BoolFlag{
+			Name: "diff", Usage: "Attach the working tree diff",
+			Category: "context", Destination: &c.Diff,
+			Sources:     cli.EnvVars(EnvVarPrefix + "DIFF"),
+			HideDefault: true,
+		},
+		&cli.BoolFlag{
+			Name: "staged", Usage: "Attach the staged diff",
+			Category: "context", Destination: &c.Staged,
+			Sources:     cli.EnvVars(EnvVarPrefix + "STAGED"),
+			HideDefault: true,
+		},
+		&cli.StringFlag{
+			Name: "diff-ref", Usage: "Attach the diff against a git ref",
+			Category: "context", Destination: &c.DiffRef,
+			Sources: cli.EnvVars(EnvVarPrefix + "DIFF_REF"),
+		},
+		&cli.IntFlag{
+			Name: "log", Usage: "Attach recent git commits",
+			Category: "context", Destination: &c.Log,
+			Sources:     cli.EnvVars(EnvVarPrefix + "LOG"),
+			HideDefault: true,
+		},
+		&cli.StringSliceFlag{
+			Name: "blame", Usage: "Attach git blame of a file range (path:start-end)",
+			Category: "context", Destination: &c.Blame,
+			Sources: cli.EnvVars(EnvVarPrefix + "BLAME"),
+			Value:   []string{}, TakesFile: true,
+		},
 		// TOOLS
 		&cli.BoolFlag{
 			Name: "web", Usage: "Enable web search",
@@ -97,6 +323,86 @@ func (c *Config) Flags() []cli.Flag {
 	}
 }
 
+// MARK: Validation
+// ============================================================================
+
+// Validate checks that the settings have valid values. Whether the model
+// supports them is checked separately, against its capabilities.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Model == "" {
+		errs = append(errs, fmt.Errorf("model: must be set"))
+	}
+	for _, fallback := range c.Fallback {
+		if strings.TrimSpace(fallback) == "" || strings.HasSuffix(fallback, "/") {
+			errs = append(errs, fmt.Errorf("fallback: expected [provider/]model, got %q", fallback))
+		}
+	}
+	if c.Provider == "fake" && c.Script == "" {
+		errs = append(errs, fmt.Errorf("script: must be set for the fake provider"))
+	}
+	if c.OTLPEndpoint != "" && !strings.HasPrefix(c.OTLPEndpoint, "http://") &&
+		!strings.HasPrefix(c.OTLPEndpoint, "https://") {
+		errs = append(errs, fmt.Errorf("otlp-endpoint: expected an http(s) URL, got %q", c.OTLPEndpoint))
+	}
+	if c.Record != "" && c.Replay != "" {
+		errs = append(errs, fmt.Errorf("record and replay are mutually exclusive"))
+	}
+	if c.Temp < 0 || c.Temp > 2 {
+		errs = append(errs, fmt.Errorf("temp: must be between 0 and 2, got %g", c.Temp))
+	}
+	switch c.Effort() {
+	case "", "low", "medium", "high":
+	default:
+		errs = append(errs, fmt.Errorf("reason: must be low, medium or high, got %q", c.Reason))
+	}
+	switch c.Summary {
+	case "auto", "concise", "detailed", "none":
+	default:
+		errs = append(errs, fmt.Errorf("reasoning-summary: must be auto, concise, detailed or none, got %q", c.Summary))
+	}
+	if c.Tokens < 0 {
+		errs = append(errs, fmt.Errorf("max: must not be negative, got %d", c.Tokens))
+	}
+	if c.Uploads < 0 {
+		errs = append(errs, fmt.Errorf("upload-size: must not be negative, got %d", c.Uploads))
+	}
+	if c.Retries < 0 {
+		errs = append(errs, fmt.Errorf("retries: must not be negative, got %d", c.Retries))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache-ttl: must not be negative, got %s", c.CacheTTL))
+	}
+	if c.AuditSize < 0 {
+		errs = append(errs, fmt.Errorf("audit-size: must not be negative, got %d", c.AuditSize))
+	}
+	if c.AuditKeep < 0 {
+		errs = append(errs, fmt.Errorf("audit-keep: must not be negative, got %d", c.AuditKeep))
+	}
+	if c.CacheSize < 0 {
+		errs = append(errs, fmt.Errorf("cache-size: must not be negative, got %d", c.CacheSize))
+	}
+	if c.Log < 0 {
+		errs = append(errs, fmt.Errorf("log: must not be negative, got %d", c.Log))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// Effort returns the reasoning effort, accepting the boolean values used
+// before efforts were selectable: true for high and false for none.
+func (c Config) Effort() string {
+	switch c.Reason {
+	case "true":
+		return "high"
+	case "false":
+		return ""
+	}
+	return c.Reason
+}
+
 // MARK: Helper Actions
 // ============================================================================
 
@@ -115,6 +421,22 @@ func (c *Config) resolveSysPrompt(
 	return nil
 }
 
+// Support reading files for system prompt additions.
+func (c *Config) resolveAdditions(
+	_ context.Context, cmd *cli.Command, additions []string,
+) error {
+	for i, addition := range additions {
+		if _, err := os.Stat(addition); err == nil {
+			file, err := os.ReadFile(addition)
+			if err != nil {
+				return fmt.Errorf("system prompt: %w", err)
+			}
+			c.Additions[i] = string(file)
+		}
+	}
+	return nil
+}
+
 // Support path globbing for file attachments.
 func (c *Config) resolveFiles(
 	_ context.Context, cmd *cli.Command, paths []string,
@@ -0,0 +1,198 @@
+// Package cfg handles configuration management.
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LocalConfigFile returns the config file of the current directory:
+// the existing .gptx or .gptx.toml file, or a new .gptx file.
+func LocalConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+	return configFileIn(dir, "."+AppName), nil
+}
+
+// GlobalConfigFile returns the global config file: the existing config or
+// config.toml file, or a new config file.
+func GlobalConfigFile() (string, error) {
+	if AppDir == "" {
+		return "", fmt.Errorf("config: no user config directory")
+	}
+	return configFileIn(AppDir, "config"), nil
+}
+
+// configFileIn prefers the dotenv file, then the TOML file, in dir.
+func configFileIn(dir string, name string) string {
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if _, err := os.Stat(path + ".toml"); err == nil {
+		return path + ".toml"
+	}
+	return path
+}
+
+// MARK: Editing
+// ============================================================================
+
+// SetValue sets a key in a config file, outside of profile sections.
+// An existing assignment is replaced in place; otherwise the key is added
+// after the file's other settings. The file is created if needed.
+func SetValue(path string, key Key, value string) error {
+	if err := key.Validate(value); err != nil {
+		return err
+	}
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	toml := filepath.Ext(path) == ".toml"
+	line := key.Env + "=" + dotenvValue(value)
+	if toml {
+		line = tomlAssignment(key, value)
+	}
+
+	start, end := findAssignment(lines, key, toml)
+	if start < 0 {
+		// Insert after the last setting before the first section
+		start = sectionStart(lines)
+		for start > 0 && strings.TrimSpace(lines[start-1]) == "" {
+			start--
+		}
+		end = start
+	}
+
+	lines = append(lines[:start], append([]string{line}, lines[end:]...)...)
+	return writeLines(path, lines)
+}
+
+// UnsetValue removes a key from a config file, outside of profile sections.
+// It reports whether the key was set in the file.
+func UnsetValue(path string, key Key) (bool, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return false, err
+	}
+
+	start, end := findAssignment(lines, key, filepath.Ext(path) == ".toml")
+	if start < 0 {
+		return false, nil
+	}
+	lines = append(lines[:start], lines[end:]...)
+	return true, writeLines(path, lines)
+}
+
+// findAssignment returns the range of lines [start, end) that assign key
+// before the first section, or -1 if the key is not set.
+func findAssignment(lines []string, key Key, toml bool) (int, int) {
+	name := regexp.QuoteMeta(key.Env)
+	if toml {
+		name = regexp.QuoteMeta(key.Name)
+	}
+	pattern := regexp.MustCompile(`^\s*(export\s+)?` + name + `\s*=\s*(.*)$`)
+
+	for i, line := range lines[:sectionStart(lines)] {
+		match := pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		// Multi-line values continue until their closing delimiter
+		value, end := strings.TrimSpace(match[2]), i+1
+		for _, delim := range []string{`"""`, `'''`, `"`, `'`, `[`} {
+			if !strings.HasPrefix(value, delim) {
+				continue
+			}
+			closing := map[string]string{"[": "]"}[delim]
+			if closing == "" {
+				closing = delim
+			}
+			if !strings.Contains(value[len(delim):], closing) {
+				for end < len(lines) && !strings.Contains(lines[end], closing) {
+					end++
+				}
+				end = min(end+1, len(lines))
+			}
+			break
+		}
+		return i, end
+	}
+	return -1, -1
+}
+
+// sectionStart returns the index of the first section header.
+func sectionStart(lines []string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "[") &&
+			!strings.Contains(line, "=") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// plainValue matches dotenv values that need no quotes.
+var plainValue = regexp.MustCompile(`^[\w.,:/@+*-]*$`)
+
+// dotenvValue quotes a dotenv value if needed. Single quotes are literal;
+// otherwise characters that double quotes would expand are escaped.
+func dotenvValue(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	} else if !strings.ContainsAny(value, "'\n") {
+		return "'" + value + "'"
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`).Replace(value)
+	return `"` + escaped + `"`
+}
+
+// tomlAssignment formats a TOML assignment of a valid value of the key's type.
+func tomlAssignment(key Key, value string) string {
+	literal := strconv.Quote(value)
+	switch key.Type {
+	case "bool":
+		b, _ := strconv.ParseBool(value)
+		literal = strconv.FormatBool(b)
+	case "int", "float":
+		literal = value
+	case "list", "lines":
+		var items []string
+		for _, item := range strings.Split(value, key.Separator()) {
+			items = append(items, strconv.Quote(item))
+		}
+		literal = "[" + strings.Join(items, ", ") + "]"
+	}
+	return key.Name + " = " + literal
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("config %q: %w", path, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+func writeLines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("config %q: %w", path, err)
+	}
+	data := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		return fmt.Errorf("config %q: %w", path, err)
+	}
+	return nil
+}
@@ -2,8 +2,12 @@
 package cfg
 
 import (
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -15,6 +19,16 @@ const AppName string = "gptx"
 // AppName is the application name.
 const EnvVarPrefix string = "GPTX_"
 
+// ListSeparator separates the items of list environment variables.
+const ListSeparator string = ","
+
+// LineSeparator separates the items of line lists, whose items can contain
+// commas, in environment variables.
+const LineSeparator string = "\n"
+
+// ProfileEnvVar selects the configuration profile.
+const ProfileEnvVar string = EnvVarPrefix + "PROFILE"
+
 // AppDir is the user config directory for the app.
 var AppDir string = func() string {
 	configDir, _ := os.UserConfigDir()
@@ -24,6 +38,14 @@ var AppDir string = func() string {
 	return filepath.Join(configDir, AppName)
 }()
 
+// Profile is the profile selected when the config files were loaded.
+var Profile string
+
+// Sources maps environment variables to where their values came from:
+// "env" for the process environment, or a config file path, followed by
+// the profile section for profile values.
+var Sources = map[string]string{}
+
 // EnvMap returns the current environment variables as a map.
 func EnvMap() map[string]string {
 	m := make(map[string]string)
@@ -36,22 +58,128 @@ func EnvMap() map[string]string {
 	return m
 }
 
+// MARK: Config Files
+// ============================================================================
+
+// profileHeader matches profile section headers, e.g. [profile.review].
+var profileHeader = regexp.MustCompile(`^\[profile\.([\w.-]+)\]$`)
+
+// inheritsKey names the parent profile inside a profile section.
+const inheritsKey = "INHERITS"
+
+// globalOnly are the settings that only the environment, flags and the
+// global config can set. They run commands or choose where the API key is
+// sent, so project config files, which come with cloned repositories, can't
+// set them.
+var globalOnly = []string{
+	EnvVarPrefix + "HOOKS", EnvVarPrefix + "API_KEY_CMD", EnvVarPrefix + "BASE_URL",
+}
+
+// configFile is a parsed .gptx file.
+type configFile struct {
+	path     string                       // File path
+	base     map[string]string            // Values outside of sections
+	profiles map[string]map[string]string // Values of each profile section
+}
+
+// layer is a set of values from a single source.
+type layer struct {
+	source string
+	values map[string]string
+}
+
 // LoadConfigFiles loads .gptx files in Git-like fashion:
 // - Current directory and parent dirs (for project settings)
 // - User's config directory (for global settings)
-func LoadConfigFiles() {
-	godotenv.Load(ConfigFiles()...)
+//
+// Nearer files take precedence. If a profile is selected, by name or through
+// GPTX_PROFILE, its sections override the files' base values, and the
+// profiles it inherits from apply beneath it. Environment variables that are
+// already set are never overridden.
+//
+// Keys are the settings known to the app. Unknown settings are returned
+// as warnings rather than failing the load.
+func LoadConfigFiles(profile string, keys []Key) ([]error, error) {
+	var files []configFile
+	var warnings []error
+	for _, path := range ConfigFiles() {
+		var file configFile
+		var err error
+		file, warnings, err = readConfigFile(path, keys, warnings)
+		if err != nil {
+			return warnings, err
+		}
+		warnings = file.dropGlobalOnly(warnings)
+		files = append(files, file)
+	}
+
+	// Record values that come from the process environment
+	for key := range EnvMap() {
+		if strings.HasPrefix(key, EnvVarPrefix) {
+			Sources[key] = "env"
+		}
+	}
+
+	// Resolve the selected profile, which may itself be set in a file
+	if profile == "" {
+		profile = os.Getenv(ProfileEnvVar)
+	}
+	for _, file := range files {
+		if profile == "" {
+			profile = file.base[ProfileEnvVar]
+		}
+	}
+
+	// Order layers from highest to lowest precedence
+	var layers []layer
+	if profile != "" {
+		chain, err := profileChain(files, profile)
+		if err != nil {
+			return warnings, err
+		}
+		for _, name := range chain {
+			for _, file := range files {
+				if values, ok := file.profiles[name]; ok {
+					source := file.path + " [profile." + name + "]"
+					layers = append(layers, layer{source, values})
+				}
+			}
+		}
+	}
+	for _, file := range files {
+		layers = append(layers, layer{file.path, file.base})
+	}
+
+	// Apply values that are not already set
+	for _, layer := range layers {
+		for key, value := range layer.values {
+			if _, ok := os.LookupEnv(key); ok || key == inheritsKey {
+				continue
+			}
+			os.Setenv(key, value)
+			Sources[key] = layer.source
+		}
+	}
+
+	Profile = profile
+	return warnings, nil
 }
 
 // ConfigFiles returns paths to all relevant configuration files.
+// Within a directory, the dotenv file takes precedence over the TOML file.
 func ConfigFiles() []string {
 	var files []string
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
 
 	// Look for .gptx files in current directory and parent directories
 	for dir, err := os.Getwd(); err == nil; dir = filepath.Dir(dir) {
-		configFile := filepath.Join(dir, "."+AppName)
-		if _, err := os.Stat(configFile); err == nil {
-			files = append(files, configFile)
+		for _, name := range []string{"." + AppName, "." + AppName + ".toml"} {
+			if configFile := filepath.Join(dir, name); exists(configFile) {
+				files = append(files, configFile)
+			}
 		}
 
 		// Stop at root directory
@@ -62,16 +190,245 @@ func ConfigFiles() []string {
 
 	// Add global config if it exists
 	if AppDir != "" {
-		globalConfig := filepath.Join(AppDir, "config")
-		if _, err := os.Stat(globalConfig); err == nil {
-			files = append(files, globalConfig)
+		for _, name := range []string{"config", "config.toml"} {
+			if globalConfig := filepath.Join(AppDir, name); exists(globalConfig) {
+				files = append(files, globalConfig)
+			}
 		}
 	}
 
 	return files
 }
 
-// Auto-load configuration
-func init() {
-	LoadConfigFiles()
+// CheckConfigFile parses a config file without loading it, returning its
+// warnings and the error that would fail loading it.
+func CheckConfigFile(path string, keys []Key) ([]error, error) {
+	file, warnings, err := readConfigFile(path, keys, nil)
+	if err != nil {
+		return warnings, err
+	}
+	return file.dropGlobalOnly(warnings), nil
+}
+
+// isGlobalConfig reports whether path is the global config file.
+func isGlobalConfig(path string) bool {
+	name := filepath.Base(path)
+	return AppDir != "" && filepath.Dir(path) == filepath.Clean(AppDir) &&
+		(name == "config" || name == "config.toml")
+}
+
+// CheckProfile checks that a profile and the profiles it inherits from
+// are defined in the config files.
+func CheckProfile(profile string, keys []Key) error {
+	var files []configFile
+	for _, path := range ConfigFiles() {
+		file, _, err := readConfigFile(path, keys, nil)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	_, err := profileChain(files, profile)
+	return err
+}
+
+// ProfileArg returns the value of the --profile flag in args, if any.
+// Profiles must be known before flags are parsed, since they determine
+// the values of the flags' environment variables.
+func ProfileArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--profile="); ok {
+			return value
+		}
+		if arg == "--profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// readConfigFile parses a dotenv or TOML config file by its extension.
+func readConfigFile(
+	path string, keys []Key, warnings []error,
+) (configFile, []error, error) {
+	if filepath.Ext(path) == ".toml" {
+		return parseTOMLConfig(path, keys, warnings)
+	}
+	return parseConfigFile(path, keys, warnings)
+}
+
+// parseConfigFile parses a dotenv file with optional profile sections.
+// Unknown app variables are appended to warnings.
+func parseConfigFile(
+	path string, keys []Key, warnings []error,
+) (configFile, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configFile{}, warnings, fmt.Errorf("config %q: %w", path, err)
+	}
+
+	file := configFile{path: path, profiles: map[string]map[string]string{}}
+	sections := map[string]*strings.Builder{"": {}}
+	current := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := profileHeader.FindStringSubmatch(trimmed); match != nil {
+			current = match[1]
+			if sections[current] == nil {
+				sections[current] = &strings.Builder{}
+			}
+			continue
+		} else if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			err := fmt.Errorf("config %q: unknown section %s", path, trimmed)
+			return configFile{}, warnings, err
+		}
+		sections[current].WriteString(line + "\n")
+	}
+
+	for name, section := range sections {
+		values, err := godotenv.Unmarshal(section.String())
+		if err != nil {
+			return configFile{}, warnings, fmt.Errorf("config %q: %w", path, err)
+		}
+		for env := range values {
+			known := env == inheritsKey || !strings.HasPrefix(env, EnvVarPrefix)
+			for _, key := range keys {
+				known = known || key.Env == env
+			}
+			if !known {
+				warnings = append(warnings, fmt.Errorf("config %q: unknown variable %s", path, env))
+			}
+		}
+
+		if name == "" {
+			file.base = values
+		} else {
+			file.profiles[name] = values
+		}
+	}
+	return file, warnings, nil
+}
+
+// parseTOMLConfig parses a TOML config file. Top-level keys set settings,
+// and [profile.name] tables set profile values. Unknown keys are appended
+// to warnings; values of the wrong type are errors.
+func parseTOMLConfig(
+	path string, keys []Key, warnings []error,
+) (configFile, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configFile{}, warnings, fmt.Errorf("config %q: %w", path, err)
+	}
+	entries, err := parseTOML(string(data))
+	if err != nil {
+		return configFile{}, warnings, fmt.Errorf("config %q: %w", path, err)
+	}
+
+	file := configFile{
+		path: path, base: map[string]string{},
+		profiles: map[string]map[string]string{},
+	}
+	for _, entry := range entries {
+		values, name := file.base, entry.Key
+		if rest, ok := strings.CutPrefix(entry.Key, "profile."); ok {
+			if i := strings.LastIndex(rest, "."); i > 0 {
+				profile := rest[:i]
+				if file.profiles[profile] == nil {
+					file.profiles[profile] = map[string]string{}
+				}
+				values, name = file.profiles[profile], rest[i+1:]
+			}
+		}
+
+		// Profile inheritance is only valid within profiles
+		if name == "inherits" && entry.Key != name {
+			inherits, ok := entry.Value.(string)
+			if !ok {
+				err := fmt.Errorf("config %q: line %d: inherits: expected a string value", path, entry.Line)
+				return configFile{}, warnings, err
+			}
+			values[inheritsKey] = inherits
+			continue
+		}
+
+		key, ok := findKey(keys, name)
+		if !ok {
+			warnings = append(warnings, fmt.Errorf("config %q: line %d: unknown key %q", path, entry.Line, entry.Key))
+			continue
+		}
+		value, err := key.envValue(entry.Value)
+		if err != nil {
+			return configFile{}, warnings, fmt.Errorf("config %q: line %d: %w", path, entry.Line, err)
+		}
+		values[key.Env] = value
+	}
+	return file, warnings, nil
+}
+
+// findKey returns the key with the given name.
+func findKey(keys []Key, name string) (Key, bool) {
+	for _, key := range keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// dropGlobalOnly removes the global-only settings of a project config file,
+// appending a warning for each.
+func (f configFile) dropGlobalOnly(warnings []error) []error {
+	if isGlobalConfig(f.path) {
+		return warnings
+	}
+	sections := []map[string]string{f.base}
+	for _, name := range slices.Sorted(maps.Keys(f.profiles)) {
+		sections = append(sections, f.profiles[name])
+	}
+	for _, values := range sections {
+		for _, env := range globalOnly {
+			if _, ok := values[env]; ok {
+				delete(values, env)
+				warnings = append(warnings, fmt.Errorf(
+					"config %q: %s can only be set in the global config, ignoring it", f.path, env))
+			}
+		}
+	}
+	return warnings
+}
+
+// profileChain returns a profile followed by the profiles it inherits from.
+func profileChain(files []configFile, profile string) ([]string, error) {
+	var chain []string
+	for name := profile; name != ""; {
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("profile %q: inheritance cycle", profile)
+			}
+		}
+
+		// Find the profile and its parent, nearest file first
+		found, parent := false, ""
+		for _, file := range files {
+			if values, ok := file.profiles[name]; ok {
+				if parent == "" {
+					parent = values[inheritsKey]
+				}
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
+
+		chain = append(chain, name)
+		name = parent
+	}
+	return chain, nil
 }
@@ -0,0 +1,54 @@
+// Package cfg handles configuration management.
+package cfg
+
+import (
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// LinesFlag is a list flag for items that can contain commas, such as shell
+// commands. Each use of the flag adds an item, and environment variables
+// separate their items with newlines.
+type LinesFlag = cli.FlagBase[[]string, cli.NoConfig, lines]
+
+// lines is the value of a LinesFlag.
+type lines struct {
+	destination *[]string
+	set         bool // Whether the default was replaced
+}
+
+// Create creates a value that starts with the default items.
+func (l lines) Create(items []string, destination *[]string, _ cli.NoConfig) cli.Value {
+	*destination = append([]string{}, items...)
+	return &lines{destination: destination}
+}
+
+// ToString formats items one per line.
+func (l lines) ToString(items []string) string {
+	return strings.Join(items, LineSeparator)
+}
+
+// Set adds the items of a value, replacing the default items.
+func (l *lines) Set(value string) error {
+	if !l.set {
+		*l.destination, l.set = []string{}, true
+	}
+	for _, item := range strings.Split(value, LineSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			*l.destination = append(*l.destination, item)
+		}
+	}
+	return nil
+}
+
+func (l *lines) String() string {
+	if l.destination == nil {
+		return ""
+	}
+	return l.ToString(*l.destination)
+}
+
+func (l *lines) Get() any {
+	return *l.destination
+}
@@ -0,0 +1,139 @@
+// Package cfg handles configuration management.
+package cfg
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Key is a setting that can be set in config files.
+type Key struct {
+	Name string // Key in structured config files (e.g. "max_tokens")
+	Env  string // Environment variable (e.g. "GPTX_MAX_TOKENS")
+	Type string // Value type: string, bool, int, float, duration, list or lines
+}
+
+// FlagKeys returns the config file keys of flags with app environment
+// variables. A key's name is its environment variable without the prefix,
+// in lowercase: GPTX_MAX_TOKENS is set with max_tokens.
+func FlagKeys(flags []cli.Flag) []Key {
+	var keys []Key
+	for _, flag := range flags {
+		doc, ok := flag.(cli.DocGenerationFlag)
+		if !ok {
+			continue
+		}
+
+		var kind string
+		switch flag.(type) {
+		case *cli.BoolFlag, *cli.BoolWithInverseFlag:
+			kind = "bool"
+		case *cli.IntFlag:
+			kind = "int"
+		case *cli.Float64Flag:
+			kind = "float"
+		case *cli.DurationFlag:
+			kind = "duration"
+		case *cli.StringSliceFlag:
+			kind = "list"
+		case *LinesFlag:
+			kind = "lines"
+		default:
+			kind = "string"
+		}
+
+		for _, env := range doc.GetEnvVars() {
+			if name, ok := strings.CutPrefix(env, EnvVarPrefix); ok {
+				keys = append(keys, Key{
+					Name: strings.ToLower(name), Env: env, Type: kind,
+				})
+			}
+		}
+	}
+	return keys
+}
+
+// envValue converts a structured config value to its environment variable
+// representation, checking it against the key's type.
+func (k Key) envValue(value any) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		if k.Type == "bool" {
+			return strconv.FormatBool(v), nil
+		}
+	case int64:
+		switch k.Type {
+		case "int", "float":
+			return strconv.FormatInt(v, 10), nil
+		}
+	case float64:
+		if k.Type == "float" {
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	case []any:
+		if separator := k.Separator(); separator != "" {
+			items := make([]string, len(v))
+			for i, item := range v {
+				str, ok := item.(string)
+				if !ok || strings.Contains(str, separator) {
+					return "", fmt.Errorf("%s: list items must be strings without %q", k.Name, separator)
+				}
+				items[i] = str
+			}
+			return strings.Join(items, separator), nil
+		}
+	case string:
+		switch k.Type {
+		case "string", "list", "lines":
+			return v, nil
+		case "duration":
+			if _, err := time.ParseDuration(v); err != nil {
+				return "", fmt.Errorf("%s: %w", k.Name, err)
+			}
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("%s: expected a %s value", k.Name, k.Type)
+}
+
+// Separator returns the separator of the items of a list key's environment
+// variable, or "" if the key isn't a list.
+func (k Key) Separator() string {
+	switch k.Type {
+	case "list":
+		return ListSeparator
+	case "lines":
+		return LineSeparator
+	}
+	return ""
+}
+
+// GlobalOnly reports whether only the global config file, rather than
+// project config files, can set the key.
+func (k Key) GlobalOnly() bool {
+	return slices.Contains(globalOnly, k.Env)
+}
+
+// Validate checks that an environment variable value has the key's type.
+func (k Key) Validate(value string) error {
+	var err error
+	switch k.Type {
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int":
+		_, err = strconv.ParseInt(value, 0, 64)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "duration":
+		_, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: expected a %s value", k.Name, k.Type)
+	}
+	return nil
+}
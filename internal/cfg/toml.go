@@ -0,0 +1,364 @@
+// Package cfg handles configuration management.
+package cfg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlEntry is a key-value pair from a TOML document.
+type tomlEntry struct {
+	Key   string // Dotted key, including the enclosing table
+	Value any    // string, int64, float64, bool or []any
+	Line  int    // Line number of the key
+}
+
+// parseTOML parses the subset of TOML used by config files: tables, dotted
+// keys, strings, numbers, booleans and arrays. Dates, inline tables and
+// arrays of tables are not supported. Errors report the line number.
+func parseTOML(data string) ([]tomlEntry, error) {
+	p := &tomlParser{src: data, line: 1}
+	var entries []tomlEntry
+	table := ""
+	seen := map[string]bool{}
+
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return entries, nil
+		}
+
+		line := p.line
+		if p.peek() == '[' {
+			p.next()
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ']' after table name")
+			}
+			table = key
+		} else {
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume('=') {
+				return nil, p.errorf("expected '=' after key %q", key)
+			}
+			p.skipSpace(false)
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if table != "" {
+				key = table + "." + key
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("line %d: duplicate key %q", line, key)
+			}
+			seen[key] = true
+			entries = append(entries, tomlEntry{Key: key, Value: value, Line: line})
+		}
+
+		// Only a comment may follow on the same line
+		p.skipSpace(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("unexpected %q at end of line", p.peek())
+		}
+	}
+}
+
+// tomlParser is a cursor over a TOML document.
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.src) }
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+func (p *tomlParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// consume skips spaces and then c, reporting whether c was found.
+func (p *tomlParser) consume(c byte) bool {
+	p.skipSpace(false)
+	if !p.eof() && p.peek() == c {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and comments, and newlines if requested.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.next()
+		case c == '\n' && newlines:
+			p.next()
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parseKey parses a bare, quoted or dotted key.
+func (p *tomlParser) parseKey() (string, error) {
+	var parts []string
+	for {
+		p.skipSpace(false)
+		if p.eof() {
+			return "", p.errorf("expected key")
+		}
+
+		var part string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			value, err := p.parseString()
+			if err != nil {
+				return "", err
+			}
+			part = value
+		case isBareKeyChar(c):
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.next()
+			}
+			part = p.src[start:p.pos]
+		default:
+			return "", p.errorf("invalid key character %q", c)
+		}
+
+		parts = append(parts, part)
+		if !p.consume('.') {
+			return strings.Join(parts, "."), nil
+		}
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// parseValue parses a string, number, boolean or array.
+func (p *tomlParser) parseValue() (any, error) {
+	if p.eof() || p.peek() == '\n' {
+		return nil, p.errorf("expected value")
+	}
+
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return nil, p.errorf("inline tables are not supported")
+	}
+
+	// Read a bare token: boolean or number
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n#,]", rune(p.peek())) {
+		p.next()
+	}
+	token := p.src[start:p.pos]
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	if number, ok := parseNumber(token); ok {
+		return number, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// TOML numbers: decimal integers without leading zeros, and floats with a
+// fraction, an exponent or both. Underscores are removed first.
+var (
+	tomlInt   = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)$`)
+	tomlFloat = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$|^[+-]?(inf|nan)$`)
+)
+
+// parseNumber parses a TOML integer, as int64, or float, as float64.
+// Integers are decimal, or hexadecimal, octal or binary with a 0x, 0o or
+// 0b prefix.
+func parseNumber(token string) (any, bool) {
+	// Underscores must be between digits
+	isDigit := isDecDigit
+	if strings.HasPrefix(token, "0x") {
+		isDigit = isHexDigit
+	}
+	for i := range len(token) {
+		if token[i] == '_' && (i == 0 || i == len(token)-1 ||
+			!isDigit(token[i-1]) || !isDigit(token[i+1])) {
+			return nil, false
+		}
+	}
+	number := strings.ReplaceAll(token, "_", "")
+
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if digits, ok := strings.CutPrefix(number, prefix); ok {
+			i, err := strconv.ParseInt(digits, base, 64)
+			return i, err == nil && !strings.ContainsAny(digits, "+-")
+		}
+	}
+	if tomlInt.MatchString(number) {
+		i, err := strconv.ParseInt(number, 10, 64)
+		return i, err == nil
+	}
+	if tomlFloat.MatchString(number) {
+		f, err := strconv.ParseFloat(number, 64)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+func isDecDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// parseArray parses an array, which may span multiple lines.
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.next() // opening bracket
+	values := []any{}
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return values, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipSpace(true)
+		if !p.eof() && p.peek() == ',' {
+			p.next()
+		} else if p.eof() || p.peek() != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// parseString parses basic, literal and multi-line strings.
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.next()
+	multiline := strings.HasPrefix(p.src[p.pos:], string([]byte{quote, quote}))
+	if multiline {
+		p.next()
+		p.next()
+		// A newline right after the opening delimiter is trimmed
+		if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+			p.next()
+		}
+		if !p.eof() && p.peek() == '\n' {
+			p.next()
+		}
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.peek()
+		if c == quote {
+			delim := string([]byte{quote, quote, quote})
+			if !multiline {
+				p.next()
+				return b.String(), nil
+			} else if strings.HasPrefix(p.src[p.pos:], delim) {
+				p.pos += len(delim)
+				return b.String(), nil
+			}
+		}
+		if c == '\n' && !multiline {
+			return "", p.errorf("newline in string")
+		}
+
+		p.next()
+		if c != '\\' || quote == '\'' {
+			b.WriteByte(c)
+			continue
+		}
+
+		// Escape sequences in basic strings
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		switch e := p.next(); e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(e)
+		case 'u', 'U':
+			size := 4
+			if e == 'U' {
+				size = 8
+			}
+			if p.pos+size > len(p.src) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			p.pos += size
+			b.WriteRune(rune(code))
+		case '\n':
+			// Line ending backslash trims the following whitespace
+			if !multiline {
+				return "", p.errorf("newline in string")
+			}
+			for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+				p.next()
+			}
+		default:
+			return "", p.errorf("invalid escape sequence \\%c", e)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cfg
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []tomlEntry
+	}{
+		{"empty", "", nil},
+		{"comments", "# comment\n\n  # indented\nmodel = \"gpt-4.1\" # trailing\n",
+			[]tomlEntry{{"model", "gpt-4.1", 4}}},
+		{"basic string", `s = "tab\tquote\" slash\\ \u00e9 \U0001F600"`,
+			[]tomlEntry{{"s", "tab\tquote\" slash\\ é 😀", 1}}},
+		{"literal string", `s = 'C:\path\n # not a comment'`,
+			[]tomlEntry{{"s", `C:\path\n # not a comment`, 1}}},
+		{"multi-line string", "s = \"\"\"\nline 1\nline \\\n    2\"\"\"",
+			[]tomlEntry{{"s", "line 1\nline 2", 1}}},
+		{"multi-line literal", "s = '''\nraw \\n\nlines'''",
+			[]tomlEntry{{"s", "raw \\n\nlines", 1}}},
+		{"integers", "a = 42\nb = -7\nc = +0\nd = 1_000\ne = 0xdead_BEEF\nf = 0o17\ng = 0b101",
+			[]tomlEntry{
+				{"a", int64(42), 1}, {"b", int64(-7), 2}, {"c", int64(0), 3},
+				{"d", int64(1000), 4}, {"e", int64(0xdeadbeef), 5}, {"f", int64(15), 6},
+				{"g", int64(5), 7},
+			}},
+		{"floats", "a = 0.5\nb = -1e3\nc = 6.02E+23\nd = 1_000.5",
+			[]tomlEntry{
+				{"a", 0.5, 1}, {"b", -1000.0, 2}, {"c", 6.02e23, 3}, {"d", 1000.5, 4},
+			}},
+		{"booleans", "a = true\nb = false",
+			[]tomlEntry{{"a", true, 1}, {"b", false, 2}}},
+		{"arrays", "a = []\nb = [\n  \"x, y\", # comment\n  'z',\n]\nc = [[1, 2], [true]]",
+			[]tomlEntry{
+				{"a", []any{}, 1},
+				{"b", []any{"x, y", "z"}, 2},
+				{"c", []any{[]any{int64(1), int64(2)}, []any{true}}, 6},
+			}},
+		{"tables", "top = 1\n[profile.work]\nmodel = \"o3\"\n[ profile . \"a.b\" ]\nretries = 2",
+			[]tomlEntry{
+				{"top", int64(1), 1},
+				{"profile.work.model", "o3", 3},
+				{"profile.a.b.retries", int64(2), 5},
+			}},
+		{"dotted and quoted keys", "a.b = 1\n\"c d\" = 2\n'e' = 3",
+			[]tomlEntry{{"a.b", int64(1), 1}, {"c d", int64(2), 2}, {"e", int64(3), 3}}},
+		{"windows line endings", "a = 1\r\nb = 'x'\r\n",
+			[]tomlEntry{{"a", int64(1), 1}, {"b", "x", 2}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTOML(test.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseTOML() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLSpecialFloats(t *testing.T) {
+	entries, err := parseTOML("a = inf\nb = -inf\nc = nan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := entries[0].Value.(float64); !math.IsInf(v, 1) {
+		t.Errorf("inf = %g", v)
+	}
+	if v := entries[1].Value.(float64); !math.IsInf(v, -1) {
+		t.Errorf("-inf = %g", v)
+	}
+	if v := entries[2].Value.(float64); !math.IsNaN(v) {
+		t.Errorf("nan = %g", v)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string // Substring of the error
+	}{
+		{"leading zero", "retries = 010", `line 1: invalid value "010"`},
+		{"leading zero float", "temp = 01.5", `invalid value "01.5"`},
+		{"signed hex", "a = -0x10", `invalid value "-0x10"`},
+		{"bad digits", "a = 0b102", `invalid value "0b102"`},
+		{"empty prefix", "a = 0x", `invalid value "0x"`},
+		{"loose underscore", "a = 1__0", `invalid value "1__0"`},
+		{"trailing underscore", "a = 10_", `invalid value "10_"`},
+		{"exponent underscore", "a = 1e_3", `invalid value "1e_3"`},
+		{"bare fraction", "a = .5", `invalid value ".5"`},
+		{"trailing dot", "a = 1.", `invalid value "1."`},
+		{"bare word", "a = yes", `invalid value "yes"`},
+		{"missing value", "a =\n", "line 1: expected value"},
+		{"missing equals", "a 1", `expected '=' after key "a"`},
+		{"duplicate key", "a = 1\n\na = 2", `line 3: duplicate key "a"`},
+		{"duplicate in table", "[t]\na = 1\n[t]\na = 2", `duplicate key "t.a"`},
+		{"unterminated string", "a = \"abc", "unterminated string"},
+		{"newline in string", "a = \"ab\nc\"", "line 1: newline in string"},
+		{"invalid escape", `a = "\q"`, `invalid escape sequence \q`},
+		{"invalid unicode", `a = "\uZZZZ"`, "invalid unicode escape"},
+		{"unterminated array", "a = [1,\n", "unterminated array"},
+		{"missing comma", "a = [1 2]", "expected ',' or ']' in array"},
+		{"inline table", "a = {b = 1}", "inline tables are not supported"},
+		{"array of tables", "[[a]]", "arrays of tables are not supported"},
+		{"unclosed table", "[a\nb = 1", "expected ']' after table name"},
+		{"trailing text", "a = 1 b", "line 1: unexpected 'b' at end of line"},
+		{"invalid key", "a! = 1", `expected '=' after key "a"`},
+		{"invalid key start", "!a = 1", "invalid key character '!'"},
+		{"empty key", "= 1", "invalid key character '='"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTOML(test.doc)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("parseTOML(%q) error = %v, want %q", test.doc, err, test.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,33 @@
-// Package events provides event handling for model interactions.
 package events
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/mohdfareed/gptx-cli/internal/cfg"
 	"github.com/mohdfareed/gptx-cli/internal/tools"
 )
 
 // Callbacks defines handlers for various model events.
 // Each field is a function that will be called when the corresponding event occurs.
-// This simpler approach replaces the pub/sub channel-based system.
+// Callbacks is a Handler, adapting typed events to the string-based handlers.
 type Callbacks struct {
 	// Model lifecycle events
-	OnStart func(cfg.Config) // When model starts
-	OnError func(error)      // Error handling
-	OnDone  func(string)     // When model completes (with usage stats)
+	OnStart    func(cfg.Config)            // When model starts
+	OnError    func(error)                 // Error handling
+	OnRetry    func(error, time.Duration)  // Before a failed request is retried
+	OnRoute    func(string, string)        // Backend serving a request, and why
+	OnFailover func(string, string, error) // Failed backend, next backend and error
+	OnDone     func(string)                // When model completes (with usage stats)
 
 	// Model output events
 	OnReply     func(string) // Text from the model
 	OnReasoning func(string) // Reasoning steps (when available)
 
 	// Tool-related events
-	OnToolCall   func(tools.ToolCall) // Request to execute a tool
-	OnToolResult func(string)         // Results from tool execution
+	OnToolCall     func(tools.ToolCall) // Request to execute a tool
+	OnToolProgress func(string, string) // Tool name and a delta of its arguments
+	OnToolResult   func(string)         // Results from tool execution
 }
 
 // Builder provides a fluent API for constructing callbacks.
@@ -35,13 +41,17 @@ func NewCallbacks() *Builder {
 	return &Builder{
 		callbacks: Callbacks{
 			// Default no-op handlers
-			OnStart:      func(cfg.Config) {},
-			OnError:      func(error) {},
-			OnDone:       func(string) {},
-			OnReply:      func(string) {},
-			OnReasoning:  func(string) {},
-			OnToolCall:   func(tools.ToolCall) {},
-			OnToolResult: func(string) {},
+			OnStart:        func(cfg.Config) {},
+			OnError:        func(error) {},
+			OnRetry:        func(error, time.Duration) {},
+			OnRoute:        func(string, string) {},
+			OnFailover:     func(string, string, error) {},
+			OnDone:         func(string) {},
+			OnReply:        func(string) {},
+			OnReasoning:    func(string) {},
+			OnToolCall:     func(tools.ToolCall) {},
+			OnToolProgress: func(string, string) {},
+			OnToolResult:   func(string) {},
 		},
 	}
 }
@@ -63,6 +73,25 @@ func (b *Builder) WithErrorHandler(handler func(error)) *Builder {
 	return b
 }
 
+// WithRetryHandler sets the handler for retried requests.
+func (b *Builder) WithRetryHandler(handler func(error, time.Duration)) *Builder {
+	b.callbacks.OnRetry = handler
+	return b
+}
+
+// WithRouteHandler sets the handler for routed requests.
+func (b *Builder) WithRouteHandler(handler func(backend, reason string)) *Builder {
+	b.callbacks.OnRoute = handler
+	return b
+}
+
+// WithFailoverHandler sets the handler for requests that fail over to
+// another backend.
+func (b *Builder) WithFailoverHandler(handler func(from, to string, err error)) *Builder {
+	b.callbacks.OnFailover = handler
+	return b
+}
+
 // WithDoneHandler sets the handler for the done event.
 func (b *Builder) WithDoneHandler(handler func(string)) *Builder {
 	b.callbacks.OnDone = handler
@@ -87,8 +116,73 @@ func (b *Builder) WithToolCallHandler(handler func(tools.ToolCall)) *Builder {
 	return b
 }
 
+// WithToolProgressHandler sets the handler for tool call arguments as the
+// model streams them.
+func (b *Builder) WithToolProgressHandler(handler func(name, args string)) *Builder {
+	b.callbacks.OnToolProgress = handler
+	return b
+}
+
 // WithToolResultHandler sets the handler for tool results.
 func (b *Builder) WithToolResultHandler(handler func(string)) *Builder {
 	b.callbacks.OnToolResult = handler
 	return b
 }
+
+// MARK: Adapter
+// ============================================================================
+
+// HandleEvent calls the callback of the event, if set. Web searches are
+// reported as tool calls and tool failures as errors.
+func (c Callbacks) HandleEvent(event Event) {
+	switch event := event.(type) {
+	case TurnStarted:
+		call(c.OnStart, event.Config)
+	case TextDelta:
+		call(c.OnReply, event.Text)
+	case ReasoningDelta:
+		call(c.OnReasoning, event.Text)
+	case ToolCallDelta:
+		if c.OnToolProgress != nil {
+			c.OnToolProgress(event.Name, event.Args)
+		}
+	case ToolCallStarted:
+		call(c.OnToolCall, tools.ToolCall{Name: event.Name, Params: event.Args})
+	case ToolCallFinished:
+		if event.Err != nil {
+			call(c.OnError, event.Err)
+		} else {
+			call(c.OnToolResult, event.Result)
+		}
+	case WebSearch:
+		var params string
+		if event.Query != "" {
+			data, _ := json.Marshal(map[string]string{"query": event.Query})
+			params = string(data)
+		}
+		call(c.OnToolCall, tools.ToolCall{Name: "web_search", Params: params})
+	case Usage:
+		call(c.OnDone, event.String())
+	case Error:
+		call(c.OnError, event.Err)
+	case Retry:
+		if c.OnRetry != nil {
+			c.OnRetry(event.Err, event.Delay)
+		}
+	case Route:
+		if c.OnRoute != nil {
+			c.OnRoute(event.Backend, event.Reason)
+		}
+	case Failover:
+		if c.OnFailover != nil {
+			c.OnFailover(event.From, event.To, event.Err)
+		}
+	}
+}
+
+// call calls a callback, if set.
+func call[T any](callback func(T), value T) {
+	if callback != nil {
+		callback(value)
+	}
+}
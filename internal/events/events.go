@@ -0,0 +1,228 @@
+// Package events provides event handling for model interactions.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+)
+
+// Event is an event of a model interaction. Each message emits RunStarted,
+// then for each request TurnStarted, its streamed deltas, Usage and
+// TurnFinished, followed by the tool events of its calls, and finally
+// RunFinished.
+type Event interface {
+	event()
+}
+
+// Reasons a turn finished.
+const (
+	FinishCompleted = "completed"  // The model replied
+	FinishToolCalls = "tool_calls" // The model called tools
+	FinishError     = "error"      // The request failed
+)
+
+// RunStarted is emitted when a message to the model starts, before its
+// requests.
+type RunStarted struct {
+	Config cfg.Config // Configuration of the model
+}
+
+// RunFinished is emitted when a message to the model ends, after its tool
+// calls and requests.
+type RunFinished struct {
+	Err error // Error of the message, if it failed
+}
+
+// TurnStarted is emitted when a request to the model starts.
+type TurnStarted struct {
+	Config cfg.Config // Configuration of the request
+}
+
+// TextDelta is text streamed from the model's reply.
+type TextDelta struct {
+	Text string
+}
+
+// ReasoningDelta is text streamed from the model's reasoning summary.
+type ReasoningDelta struct {
+	Text string
+}
+
+// ToolCallDelta is a part of a tool call's arguments, streamed while the
+// model writes them.
+type ToolCallDelta struct {
+	ID   string // Call ID
+	Name string // Tool name
+	Args string // Arguments delta
+}
+
+// ToolCallStarted is emitted before a tool call runs.
+type ToolCallStarted struct {
+	ID   string // Call ID, linking the call to its result
+	Name string // Tool name
+	Args string // Arguments as a JSON string
+}
+
+// ToolCallFinished is emitted after a tool call runs.
+type ToolCallFinished struct {
+	ID       string        // Call ID, linking the result to its call
+	Name     string        // Tool name
+	Result   string        // Tool output, if it succeeded
+	Err      error         // Tool error, if it failed
+	Duration time.Duration // Time the tool ran
+}
+
+// WebSearch is emitted when the model searches the web.
+type WebSearch struct {
+	Query string // Search query, if known
+}
+
+// Usage is the token usage of a request.
+type Usage struct {
+	InputTokens     int `json:"input_tokens"`
+	CachedTokens    int `json:"cached_tokens,omitempty"`
+	OutputTokens    int `json:"output_tokens"`
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	TotalTokens     int `json:"total_tokens"`
+}
+
+// TurnFinished is emitted when a request to the model ends.
+type TurnFinished struct {
+	Reason string // Completed, tool calls, error or why it was incomplete
+}
+
+// Error is emitted when a request fails.
+type Error struct {
+	Err error
+}
+
+// Retry is emitted before a failed request is retried.
+type Retry struct {
+	Err   error         // Error of the failed attempt
+	Delay time.Duration // Wait before the retry
+}
+
+// Route is emitted with the backend serving a request.
+type Route struct {
+	Backend string // Backend as provider/model
+	Reason  string // Why the backend was selected
+}
+
+// Failover is emitted when a backend fails a request that moves on to the
+// next backend.
+type Failover struct {
+	From string // Backend that failed, as provider/model
+	To   string // Backend tried next
+	Err  error  // Error of the failed backend
+}
+
+func (RunStarted) event()       {}
+func (RunFinished) event()      {}
+func (TurnStarted) event()      {}
+func (TextDelta) event()        {}
+func (ReasoningDelta) event()   {}
+func (ToolCallDelta) event()    {}
+func (ToolCallStarted) event()  {}
+func (ToolCallFinished) event() {}
+func (WebSearch) event()        {}
+func (Usage) event()            {}
+func (TurnFinished) event()     {}
+func (Error) event()            {}
+func (Retry) event()            {}
+func (Route) event()            {}
+func (Failover) event()         {}
+
+// String returns the usage as indented JSON.
+func (u Usage) String() string {
+	data, _ := json.MarshalIndent(u, "", "  ")
+	return string(data)
+}
+
+// ParseUsage parses a usage JSON string, as returned by String, returning
+// zero usage if it is invalid.
+func ParseUsage(usage string) Usage {
+	var parsed Usage
+	_ = json.Unmarshal([]byte(usage), &parsed)
+	return parsed
+}
+
+// Add returns the sum of two usages.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:     u.InputTokens + other.InputTokens,
+		CachedTokens:    u.CachedTokens + other.CachedTokens,
+		OutputTokens:    u.OutputTokens + other.OutputTokens,
+		ReasoningTokens: u.ReasoningTokens + other.ReasoningTokens,
+		TotalTokens:     u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// MARK: Handlers
+// ============================================================================
+
+// Handler handles model events.
+type Handler interface {
+	HandleEvent(Event)
+}
+
+// HandlerFunc is a function that implements Handler.
+type HandlerFunc func(Event)
+
+// HandleEvent calls the function.
+func (f HandlerFunc) HandleEvent(event Event) {
+	f(event)
+}
+
+// Emit sends an event to a handler, if any.
+func Emit(handler Handler, event Event) {
+	if handler != nil {
+		handler.HandleEvent(event)
+	}
+}
+
+// Bus is a handler that delivers events to its subscribers, in the order
+// they subscribed.
+type Bus struct {
+	subscribers []subscriber
+	next        int // ID of the next subscriber
+	mu          sync.RWMutex
+}
+
+// subscriber is a handler subscribed to a bus.
+type subscriber struct {
+	id      int
+	handler Handler
+}
+
+// Subscribe adds a handler to the bus and returns a function that removes it.
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subscribers {
+			if sub.id == id {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// HandleEvent delivers the event to every subscriber.
+func (b *Bus) HandleEvent(event Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, sub := range subscribers {
+		sub.handler.HandleEvent(event)
+	}
+}
@@ -0,0 +1,109 @@
+// Package git provides Git context providers for model messages.
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is the patch of a single file in a unified diff.
+type FileDiff struct {
+	Path   string // Path of the file after the change
+	Header string // Diff header lines before the first hunk
+	Hunks  []Hunk // Changed regions of the file
+}
+
+// Hunk is a changed region of a file.
+type Hunk struct {
+	Header string // Hunk header (e.g. "@@ -1,4 +1,5 @@ func main()")
+	Start  int    // First line of the hunk in the new file
+	Lines  []string
+}
+
+// ParseDiff splits a unified diff, as produced by git diff, into files
+// and hunks. Binary files and pure renames have no hunks.
+func ParseDiff(diff string) ([]FileDiff, error) {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, FileDiff{Header: line})
+			file, hunk = &files[len(files)-1], nil
+			// Default to the new path; refined by the +++ line
+			if _, path, ok := strings.Cut(line, " b/"); ok {
+				file.Path = path
+			}
+
+		case file == nil:
+			continue // preamble before the first file
+
+		case strings.HasPrefix(line, "@@"):
+			start, err := hunkStart(line)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, Hunk{Header: line, Start: start})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+
+		case hunk != nil:
+			hunk.Lines = append(hunk.Lines, line)
+
+		default:
+			file.Header += "\n" + line
+			if path, ok := strings.CutPrefix(line, "+++ b/"); ok {
+				file.Path = path
+			}
+		}
+	}
+	return files, nil
+}
+
+// hunkStart returns the new-file start line of a hunk header.
+func hunkStart(header string) (int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, fmt.Errorf("invalid hunk header: %s", header)
+	}
+
+	start, _, _ := strings.Cut(fields[2][1:], ",")
+	line, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hunk header: %s", header)
+	}
+	return line, nil
+}
+
+// String returns the file's patch.
+func (f FileDiff) String() string {
+	var b strings.Builder
+	b.WriteString(f.Header)
+	for _, hunk := range f.Hunks {
+		b.WriteString("\n" + hunk.String())
+	}
+	return b.String()
+}
+
+// String returns the hunk's patch.
+func (h Hunk) String() string {
+	return strings.Join(append([]string{h.Header}, h.Lines...), "\n")
+}
+
+// Numbered returns the hunk with new-file line numbers prefixed to added
+// and unchanged lines, so findings can refer to exact lines.
+func (h Hunk) Numbered() string {
+	lines := []string{h.Header}
+	number := h.Start
+	for _, line := range h.Lines {
+		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "\\") {
+			lines = append(lines, "      "+line)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%5d %s", number, line))
+		number++
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@ package main
+ package main
+ 
+-func main() {}
++func main() {
++	run()
++}
+@@ -20,2 +21,3 @@ func run() {
+ 	start()
++	stop()
+ }
+\ No newline at end of file
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/logo.png differ
+diff --git a/old name.txt b/new name.txt
+similarity index 100%
+rename from old name.txt
+rename to new name.txt
+`
+
+func TestParseDiff(t *testing.T) {
+	files, err := ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("parsed %d files, want 3", len(files))
+	}
+
+	main := files[0]
+	if main.Path != "main.go" || len(main.Hunks) != 2 {
+		t.Fatalf("main.go = %+v, want 2 hunks", main)
+	}
+	if !strings.HasSuffix(main.Header, "+++ b/main.go") {
+		t.Errorf("header = %q, want the lines before the first hunk", main.Header)
+	}
+	if main.Hunks[0].Start != 1 || main.Hunks[1].Start != 21 {
+		t.Errorf("hunks start at %d and %d, want 1 and 21", main.Hunks[0].Start, main.Hunks[1].Start)
+	}
+	if got := len(main.Hunks[0].Lines); got != 6 {
+		t.Errorf("first hunk has %d lines, want 6", got)
+	}
+	if main.String()+"\n" != sampleDiff[:strings.Index(sampleDiff, "diff --git a/logo.png")] {
+		t.Errorf("String() = %q, want the file's patch", main.String())
+	}
+
+	// Binary files and renames have no hunks
+	if files[1].Path != "logo.png" || len(files[1].Hunks) != 0 {
+		t.Errorf("logo.png = %+v, want no hunks", files[1])
+	}
+	if files[2].Path != "new name.txt" || len(files[2].Hunks) != 0 {
+		t.Errorf("rename = %+v, want the new path and no hunks", files[2])
+	}
+}
+
+func TestParseDiffErrors(t *testing.T) {
+	for _, header := range []string{"@@ -1 @@", "@@ -1,2 +x,3 @@", "@@ -1,2 1,3 @@"} {
+		if _, err := ParseDiff("diff --git a/f b/f\n" + header + "\n"); err == nil {
+			t.Errorf("ParseDiff() accepted hunk header %q", header)
+		}
+	}
+	if files, err := ParseDiff(""); err != nil || len(files) != 0 {
+		t.Errorf("ParseDiff(\"\") = %v, %v, want no files", files, err)
+	}
+}
+
+func TestNumbered(t *testing.T) {
+	files, err := ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Added and unchanged lines are numbered; removed lines are not
+	want := strings.Join([]string{
+		"@@ -20,2 +21,3 @@ func run() {",
+		"   21  \tstart()",
+		"   22 +\tstop()",
+		"   23  }",
+		"      \\ No newline at end of file",
+	}, "\n")
+	if got := files[0].Hunks[1].Numbered(); got != want {
+		t.Errorf("Numbered() =\n%s\nwant\n%s", got, want)
+	}
+
+	got := files[0].Hunks[0].Numbered()
+	if !strings.Contains(got, "      -func main() {}\n    3 +func main() {") {
+		t.Errorf("Numbered() =\n%s\nwant the removed line unnumbered", got)
+	}
+}
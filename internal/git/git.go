@@ -0,0 +1,144 @@
+// Package git provides Git context providers for model messages.
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// Provider attaches the output of a git command as message context.
+// It implements the gptx.ContextProvider interface.
+type Provider struct {
+	Label string   // Attachment label
+	Args  []string // Git command arguments
+}
+
+// Context runs the git command and returns its output as an attachment.
+// Commands with no output (e.g. a clean working tree) attach nothing.
+func (p Provider) Context(ctx context.Context) ([]gptx.Attachment, error) {
+	out, err := Run(ctx, p.Args...)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out) == "" {
+		return nil, nil
+	}
+	return []gptx.Attachment{{Label: p.Label, Content: out}}, nil
+}
+
+// Run runs a git command in the current directory and returns its output.
+func Run(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// MARK: Providers
+// ============================================================================
+
+// WorkingDiff provides the unstaged changes in the working tree.
+func WorkingDiff() Provider {
+	return Provider{Label: "git diff", Args: []string{"diff"}}
+}
+
+// StagedDiff provides the changes staged for commit.
+func StagedDiff() Provider {
+	return Provider{Label: "git diff --staged", Args: []string{"diff", "--staged"}}
+}
+
+// RefDiff provides the changes between a ref and the working tree.
+func RefDiff(ref string) Provider {
+	return Provider{
+		Label: "git diff " + ref,
+		Args:  []string{"diff", "--end-of-options", ref, "--"},
+	}
+}
+
+// Log provides the n most recent commits.
+func Log(n int) Provider {
+	count := strconv.Itoa(n)
+	return Provider{
+		Label: "git log -n " + count,
+		Args: []string{
+			"log", "-n", count, "--date=short",
+			"--format=%h %ad %an%n%w(0,4,4)%B",
+		},
+	}
+}
+
+// Blame provides the blame of a file or line range, given as
+// "path", "path:line" or "path:start-end". The range follows the last
+// colon, so paths may contain colons.
+func Blame(spec string) (Provider, error) {
+	path, lines, found := spec, "", false
+	if i := strings.LastIndex(spec, ":"); i >= 0 && isRange(spec[i+1:]) {
+		path, lines, found = spec[:i], spec[i+1:], true
+	}
+	args := []string{"blame", "--date=short"}
+	if found {
+		start, end, _ := strings.Cut(lines, "-")
+		if end == "" {
+			end = start
+		}
+		if _, err := strconv.Atoi(start); err != nil {
+			return Provider{}, fmt.Errorf("blame %q: invalid line range", spec)
+		}
+		if _, err := strconv.Atoi(end); err != nil {
+			return Provider{}, fmt.Errorf("blame %q: invalid line range", spec)
+		}
+		args = append(args, "-L", start+","+end)
+	}
+
+	args = append(args, "--", path)
+	return Provider{Label: "git blame " + spec, Args: args}, nil
+}
+
+// isRange reports whether text looks like a line range: digits and dashes.
+func isRange(text string) bool {
+	return text != "" && strings.Trim(text, "0123456789-") == ""
+}
+
+// MARK: Repository
+// ============================================================================
+
+// Info describes the repository in the current directory.
+type Info struct {
+	Root   string // Top-level directory
+	Branch string // Current branch, empty if detached
+	Commit string // Abbreviated HEAD commit
+	User   string // Configured user name
+}
+
+// RepoInfo returns information about the current repository. Fields that
+// are unavailable, such as all of them outside a repository, are empty.
+func RepoInfo(ctx context.Context) Info {
+	value := func(args ...string) string {
+		out, err := Run(ctx, args...)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(out)
+	}
+
+	return Info{
+		Root:   value("rev-parse", "--show-toplevel"),
+		Branch: value("branch", "--show-current"),
+		Commit: value("rev-parse", "--short", "HEAD"),
+		User:   value("config", "user.name"),
+	}
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// repo creates a git repository with a committed file and makes it the
+// current directory for the test.
+func repo(t *testing.T, name, content string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitRun := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", args[0], err, out)
+		}
+	}
+	gitRun("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun("add", ".")
+	gitRun("commit", "-q", "-m", "init")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestRefDiff(t *testing.T) {
+	dir := repo(t, "main.go", "package main\n")
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	attached, err := RefDiff("HEAD").Context(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attached) != 1 || !strings.Contains(attached[0].Content, "+func main() {}") {
+		t.Errorf("RefDiff(HEAD) = %+v, want the change to main.go", attached)
+	}
+
+	// Refs that look like options are refs, not options
+	out := filepath.Join(dir, "out.txt")
+	if _, err := RefDiff("--output=" + out).Context(ctx); err == nil {
+		t.Error("RefDiff(--output=...) = nil error, want an unknown revision")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("RefDiff(--output=...) wrote %s", out)
+	}
+}
+
+func TestBlameArgs(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string // Arguments after "blame --date=short"
+	}{
+		{"main.go", []string{"--", "main.go"}},
+		{"main.go:12", []string{"-L", "12,12", "--", "main.go"}},
+		{"main.go:3-9", []string{"-L", "3,9", "--", "main.go"}},
+		{"a:b.go", []string{"--", "a:b.go"}},
+		{"a:b.go:4", []string{"-L", "4,4", "--", "a:b.go"}},
+		{"notes:v2", []string{"--", "notes:v2"}},
+	}
+	for _, test := range tests {
+		provider, err := Blame(test.spec)
+		if err != nil {
+			t.Errorf("Blame(%q) error = %v", test.spec, err)
+			continue
+		}
+		want := append([]string{"blame", "--date=short"}, test.want...)
+		if !slices.Equal(provider.Args, want) {
+			t.Errorf("Blame(%q) args = %q, want %q", test.spec, provider.Args, want)
+		}
+	}
+
+	for _, spec := range []string{"main.go:-3", "main.go:1-2-3"} {
+		if _, err := Blame(spec); err == nil {
+			t.Errorf("Blame(%q) = nil error, want an invalid line range", spec)
+		}
+	}
+}
+
+func TestBlame(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file names can't contain colons on Windows")
+	}
+	repo(t, "a:b.txt", "one\ntwo\nthree\n")
+
+	provider, err := Blame("a:b.txt:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attached, err := provider.Context(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attached) != 1 {
+		t.Fatalf("Blame(a:b.txt:2) = %+v, want one attachment", attached)
+	}
+	lines := strings.Split(strings.TrimSpace(attached[0].Content), "\n")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], ") two") {
+		t.Errorf("blame = %q, want only line 2", attached[0].Content)
+	}
+}
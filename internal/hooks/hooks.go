@@ -0,0 +1,223 @@
+// Package hooks runs user commands on model lifecycle events, for
+// notifications, logging and policy checks. Each hook receives its event as
+// JSON on stdin, and pre_tool hooks can reject or rewrite tool calls.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// Hook events.
+const (
+	PreRequest   = "pre_request"   // Before each request to the model
+	PostResponse = "post_response" // After each response, with its usage
+	PreTool      = "pre_tool"      // Before a tool call runs; may reject or rewrite it
+	PostTool     = "post_tool"     // After a tool call runs, with its result
+	OnError      = "on_error"      // When a request fails
+)
+
+// Events are the events hooks can run on.
+var Events = []string{PreRequest, PostResponse, PreTool, PostTool, OnError}
+
+// Timeout limits how long a hook runs.
+const Timeout = time.Minute
+
+// ErrRejected is returned for tool calls that a pre_tool hook rejects.
+var ErrRejected = errors.New("rejected by hook")
+
+// Hook is a command run on an event.
+type Hook struct {
+	Event   string // Event that runs the hook
+	Command string // Shell command
+}
+
+// Parse parses hooks from event=command specifications.
+func Parse(specs []string) ([]Hook, error) {
+	var hooks []Hook
+	var errs []error
+	for _, spec := range specs {
+		event, command, ok := strings.Cut(spec, "=")
+		event, command = strings.TrimSpace(event), strings.TrimSpace(command)
+		switch {
+		case !ok || command == "":
+			errs = append(errs, fmt.Errorf("hook: expected event=command, got %q", spec))
+		case !slices.Contains(Events, event):
+			errs = append(errs, fmt.Errorf("hook: unknown event %q, expected one of %s",
+				event, strings.Join(Events, ", ")))
+		default:
+			hooks = append(hooks, Hook{Event: event, Command: command})
+		}
+	}
+	return hooks, errors.Join(errs...)
+}
+
+// MARK: Payloads
+// ============================================================================
+
+// Payload is the JSON a hook receives on stdin. Fields are set by event.
+type Payload struct {
+	Event    string          `json:"event"`
+	Provider string          `json:"provider,omitempty"`    // pre_request
+	Model    string          `json:"model,omitempty"`       // pre_request
+	Reason   string          `json:"reason,omitempty"`      // post_response
+	Usage    *events.Usage   `json:"usage,omitempty"`       // post_response
+	ID       string          `json:"id,omitempty"`          // pre_tool, post_tool
+	Name     string          `json:"name,omitempty"`        // pre_tool, post_tool
+	Args     json.RawMessage `json:"args,omitempty"`        // pre_tool, post_tool
+	Result   *string         `json:"result,omitempty"`      // post_tool
+	Error    string          `json:"error,omitempty"`       // post_tool, on_error
+	Kind     gptx.ErrorKind  `json:"kind,omitempty"`        // on_error
+	Duration int64           `json:"duration_ms,omitempty"` // post_tool
+}
+
+// MARK: Runner
+// ============================================================================
+
+// Runner runs hooks on the events of a model. It is an events.Handler,
+// and its PreTool method is a gptx.ToolHook.
+type Runner struct {
+	hooks []Hook                           // Hooks, in order
+	logf  func(format string, args ...any) // Reports failing hooks
+	usage events.Usage                     // Usage of the current turn
+	args  map[string]string                // Arguments of running tool calls
+	mu    sync.Mutex
+}
+
+// NewRunner creates a runner of hooks. Hooks that fail, other than pre_tool
+// hooks rejecting calls, are reported with logf.
+func NewRunner(hooks []Hook, logf func(format string, args ...any)) *Runner {
+	return &Runner{hooks: hooks, logf: logf, args: map[string]string{}}
+}
+
+// HandleEvent runs the hooks of a model event.
+func (r *Runner) HandleEvent(event events.Event) {
+	var payload Payload
+	switch e := event.(type) {
+	case events.TurnStarted:
+		payload = Payload{Event: PreRequest, Provider: e.Config.Provider, Model: e.Config.Model}
+	case events.Usage:
+		r.mu.Lock()
+		r.usage = e
+		r.mu.Unlock()
+		return
+	case events.TurnFinished:
+		r.mu.Lock()
+		usage := r.usage
+		r.usage = events.Usage{}
+		r.mu.Unlock()
+		payload = Payload{Event: PostResponse, Reason: e.Reason, Usage: &usage}
+	case events.ToolCallStarted:
+		r.mu.Lock()
+		r.args[e.ID] = e.Args
+		r.mu.Unlock()
+		return
+	case events.ToolCallFinished:
+		r.mu.Lock()
+		args := r.args[e.ID]
+		delete(r.args, e.ID)
+		r.mu.Unlock()
+		payload = Payload{
+			Event: PostTool, ID: e.ID, Name: e.Name, Args: rawArgs(args),
+			Duration: e.Duration.Milliseconds(),
+		}
+		if e.Err != nil {
+			payload.Error = e.Err.Error()
+		} else {
+			payload.Result = &e.Result
+		}
+	case events.Error:
+		payload = Payload{Event: OnError, Error: e.Err.Error(), Kind: gptx.ErrorKindOf(e.Err)}
+	default:
+		return
+	}
+
+	for _, hook := range r.hooks {
+		if hook.Event != payload.Event {
+			continue
+		}
+		if _, err := run(context.Background(), hook, payload); err != nil {
+			r.logf("Hook %s failed: %s", hook.Event, err)
+		}
+	}
+}
+
+// PreTool runs the pre_tool hooks of a tool call, in order. A hook rejects
+// the call by exiting with an error, with its stderr as the reason, and
+// rewrites the call's arguments by printing new arguments as JSON to stdout.
+func (r *Runner) PreTool(ctx context.Context, call gptx.ToolCall) (gptx.ToolCall, error) {
+	for _, hook := range r.hooks {
+		if hook.Event != PreTool {
+			continue
+		}
+
+		output, err := run(ctx, hook, Payload{
+			Event: PreTool, ID: call.ID, Name: call.Name, Args: rawArgs(call.Arguments),
+		})
+		if err != nil {
+			return call, fmt.Errorf("%w: %s", ErrRejected, err)
+		}
+		if output = strings.TrimSpace(output); output != "" {
+			if !json.Valid([]byte(output)) {
+				return call, fmt.Errorf("%w: invalid arguments: %s", ErrRejected, output)
+			}
+			call.Arguments = output
+		}
+	}
+	return call, nil
+}
+
+// run runs a hook's command with the payload on stdin and returns its
+// output. Errors include what the command printed to stderr.
+func run(ctx context.Context, hook Hook, payload Payload) (string, error) {
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, hook.Command)
+	cmd.Env = append(os.Environ(), "GPTX_HOOK_EVENT="+hook.Event)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return "", fmt.Errorf("%s", reason)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// rawArgs returns tool call arguments as JSON, or as a JSON string if they
+// aren't valid JSON.
+func rawArgs(args string) json.RawMessage {
+	if args == "" {
+		return nil
+	}
+	if json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	data, _ := json.Marshal(args)
+	return data
+}
@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+func TestPreTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in these tests are POSIX shell commands")
+	}
+	call := gptx.ToolCall{ID: "call_1", Name: "shell", Arguments: `{"cmd":"rm -rf ."}`}
+
+	tests := []struct {
+		name     string
+		commands []string
+		wantArgs string // Arguments of the call that runs
+		wantErr  string // Rejection reason, if rejected
+	}{
+		{"allow", []string{"cat >/dev/null"}, call.Arguments, ""},
+		{"veto by exit code", []string{"exit 1"}, "", "exit status 1"},
+		{"veto with reason", []string{"echo 'not here' >&2; exit 2"}, "", "not here"},
+		{"rewrite", []string{`echo '{"cmd":"ls"}'`}, `{"cmd":"ls"}`, ""},
+		{"rewrite then veto", []string{`echo '{"cmd":"ls"}'`, "grep -q rm || exit 1"}, "", "exit status 1"},
+		{"rewrite in order", []string{`echo '{"cmd":"ls"}'`, `grep -q '"ls"' && echo '{"cmd":"pwd"}'`}, `{"cmd":"pwd"}`, ""},
+		{"invalid rewrite", []string{"echo not json"}, "", "invalid arguments: not json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var specs []string
+			for _, command := range test.commands {
+				specs = append(specs, PreTool+"="+command)
+			}
+			hookList, err := Parse(specs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runner := NewRunner(hookList, t.Logf)
+
+			checked, err := runner.PreTool(context.Background(), call)
+			if test.wantErr != "" {
+				if !errors.Is(err, ErrRejected) || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("PreTool() error = %v, want a rejection with %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checked.Arguments != test.wantArgs || checked.ID != call.ID {
+				t.Errorf("PreTool() = %+v, want arguments %s", checked, test.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	hookList, err := Parse([]string{"pre_tool = jq -c '{cmd: .args.cmd, dir: \"a,b\"}'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Hook{Event: PreTool, Command: `jq -c '{cmd: .args.cmd, dir: "a,b"}'`}
+	if len(hookList) != 1 || hookList[0] != want {
+		t.Errorf("Parse() = %+v, want %+v", hookList, want)
+	}
+
+	_, err = Parse([]string{"no-command", "on_start=true"})
+	if err == nil || !strings.Contains(err.Error(), "expected event=command") ||
+		!strings.Contains(err.Error(), `unknown event "on_start"`) {
+		t.Errorf("Parse() error = %v, want both invalid hooks", err)
+	}
+}
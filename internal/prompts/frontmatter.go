@@ -0,0 +1,125 @@
+// Package prompts loads prompt templates from the template library.
+package prompts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// frontMatterDelim opens and closes a template's front matter.
+const frontMatterDelim = "---"
+
+// Parse parses a template with optional front matter: "key: value" lines
+// between "---" lines at the start of the file. Values are strings,
+// numbers, booleans or lists of strings in brackets:
+//
+//	---
+//	description: Explain a file
+//	model: gpt-4.1
+//	temp: 0.2
+//	tools: [shell, web]
+//	files: ["*.go"]
+//	---
+//	Explain {{.file}}.
+func Parse(data string) (Template, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	rest, ok := strings.CutPrefix(data, frontMatterDelim+"\n")
+	if !ok {
+		return Template{Body: data}, nil
+	}
+
+	header, body, ok := strings.Cut(rest, "\n"+frontMatterDelim+"\n")
+	if !ok {
+		header, ok = strings.CutSuffix(rest, "\n"+frontMatterDelim)
+		if !ok {
+			return Template{}, fmt.Errorf("unterminated front matter")
+		}
+	}
+
+	tmpl := Template{Body: body}
+	for i, line := range strings.Split(header, "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := tmpl.setField(line); err != nil {
+			return Template{}, fmt.Errorf("front matter line %d: %w", i+2, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// setField sets the template field of a front matter line.
+func (t *Template) setField(line string) error {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("expected key: value")
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	var err error
+	switch key {
+	case "description":
+		t.Description, err = parseString(value)
+	case "model":
+		t.Model, err = parseString(value)
+	case "reason":
+		t.Reason, err = parseString(value)
+	case "system":
+		t.System, err = parseString(value)
+	case "temp":
+		var temp float64
+		if temp, err = strconv.ParseFloat(value, 64); err == nil {
+			t.Temp = &temp
+		}
+	case "max":
+		t.Tokens, err = strconv.Atoi(value)
+	case "tools":
+		t.Tools, err = parseList(value)
+	case "files":
+		t.Files, err = parseList(value)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// parseString parses a bare or quoted string.
+func parseString(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		return strconv.Unquote(value)
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value[1 : len(value)-1], nil
+	}
+	return value, nil
+}
+
+// parseList parses a bracketed list of strings, or a single string.
+func parseList(value string) ([]string, error) {
+	inner, ok := strings.CutPrefix(value, "[")
+	if !ok {
+		item, err := parseString(value)
+		return []string{item}, err
+	}
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok {
+		return nil, fmt.Errorf("unterminated list")
+	}
+
+	var items []string
+	for _, item := range strings.Split(inner, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		item, err := parseString(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,230 @@
+// Package prompts loads prompt templates from the template library.
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+)
+
+// ProjectDir is the project directory for app files, next to .gptx files.
+const ProjectDir = "." + cfg.AppName + ".d"
+
+// Template is a prompt template with its front matter settings.
+type Template struct {
+	Name        string // File name without the extension
+	Path        string // File path
+	Description string // Summary shown in listings
+	Body        string // Template text
+
+	// Settings applied to the config when the template is run
+	Model  string   // Model name
+	Temp   *float64 // Temperature
+	Reason string   // Reasoning effort
+	Tokens int      // Max output tokens
+	System string   // System prompt
+	Tools  []string // Enabled tools: shell, web
+	Files  []string // Attached files (globs)
+}
+
+// MARK: Library
+// ============================================================================
+
+// Dirs returns the template directories, nearest first: .gptx.d/prompts in
+// the current directory and its parents, then prompts in the app directory.
+func Dirs() []string {
+	var dirs []string
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.IsDir()
+	}
+
+	for dir, err := os.Getwd(); err == nil; dir = filepath.Dir(dir) {
+		if prompts := filepath.Join(dir, ProjectDir, "prompts"); exists(prompts) {
+			dirs = append(dirs, prompts)
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	if cfg.AppDir != "" {
+		if prompts := filepath.Join(cfg.AppDir, "prompts"); exists(prompts) {
+			dirs = append(dirs, prompts)
+		}
+	}
+	return dirs
+}
+
+// List returns the templates in the library, sorted by name. Templates in
+// nearer directories hide those of the same name further away.
+func List() ([]Template, error) {
+	var templates []Template
+	seen := map[string]bool{}
+	for _, dir := range Dirs() {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ".md")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			tmpl, err := Load(path)
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, tmpl)
+		}
+	}
+
+	slices.SortFunc(templates, func(a, b Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return templates, nil
+}
+
+// Find returns the template with the given name from the library, or
+// loads it from a file if name is a path.
+func Find(name string) (Template, error) {
+	if strings.HasSuffix(name, ".md") {
+		if _, err := os.Stat(name); err == nil {
+			return Load(name)
+		}
+	}
+
+	for _, dir := range Dirs() {
+		path := filepath.Join(dir, name+".md")
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		}
+	}
+	return Template{}, fmt.Errorf("prompt template %q not found", name)
+}
+
+// Load reads a template file.
+func Load(path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("prompt template: %w", err)
+	}
+
+	tmpl, err := Parse(string(data))
+	if err != nil {
+		return Template{}, fmt.Errorf("prompt template %q: %w", path, err)
+	}
+	tmpl.Name = strings.TrimSuffix(filepath.Base(path), ".md")
+	tmpl.Path = path
+	return tmpl, nil
+}
+
+// MARK: Rendering
+// ============================================================================
+
+// Render executes the template's body and file patterns with the given
+// data, returning the rendered template. Referencing a variable that is
+// not set is an error.
+func (t Template) Render(data map[string]any) (Template, error) {
+	render := func(text string) (string, error) {
+		parsed, err := template.New(t.Name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("prompt template %s: %w", t.Name, err)
+		}
+
+		var b strings.Builder
+		if err := parsed.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("prompt template %s: %w", t.Name, err)
+		}
+		return b.String(), nil
+	}
+
+	body, err := render(t.Body)
+	if err != nil {
+		return Template{}, err
+	}
+	t.Body = strings.TrimSpace(body)
+
+	files := make([]string, len(t.Files))
+	for i, pattern := range t.Files {
+		if files[i], err = render(pattern); err != nil {
+			return Template{}, err
+		}
+	}
+	t.Files = files
+	return t, nil
+}
+
+// Uses reports whether the template refers to a top-level data field.
+func (t Template) Uses(field string) bool {
+	if strings.Contains(t.Body, "."+field) {
+		return true
+	}
+	for _, pattern := range t.Files {
+		if strings.Contains(pattern, "."+field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the template's settings on a config. Settings for which keep
+// returns true, such as those set with flags, are left unchanged.
+func (t Template) Apply(config *cfg.Config, keep func(flag string) bool) error {
+	set := func(flag string, apply func()) {
+		if !keep(flag) {
+			apply()
+		}
+	}
+
+	if t.Model != "" {
+		set("model", func() { config.Model = t.Model })
+	}
+	if t.Temp != nil {
+		set("temp", func() { config.Temp = *t.Temp })
+	}
+	if t.Reason != "" {
+		set("reason", func() { config.Reason = t.Reason })
+	}
+	if t.Tokens != 0 {
+		set("max", func() { config.Tokens = t.Tokens })
+	}
+	if t.System != "" {
+		set("prompt", func() { config.SysPrompt = t.System })
+	}
+
+	for _, tool := range t.Tools {
+		switch tool {
+		case "shell":
+			set("shell", func() {
+				if config.Shell == "" {
+					config.Shell = "auto"
+				}
+			})
+		case "web":
+			set("web", func() { config.WebSearch = true })
+		default:
+			return fmt.Errorf("prompt template %s: unknown tool %q", t.Name, tool)
+		}
+	}
+
+	// Files add to the attached files
+	for _, pattern := range t.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("prompt template %s: file pattern %q: %w", t.Name, pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("prompt template %s: no files match %q", t.Name, pattern)
+		}
+		config.Files = append(config.Files, matches...)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package prompts loads prompt templates from the template library.
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+)
+
+// instructionFiles are the project instruction files in each directory.
+var instructionFiles = []string{
+	"AGENTS.md", filepath.Join(ProjectDir, "instructions.md"),
+}
+
+// InstructionFiles returns the instruction files that apply to the current
+// directory, from the most general to the most specific: instructions.md
+// in the app directory, then project files from the root directory down.
+func InstructionFiles() []string {
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
+
+	var project []string
+	for dir, err := os.Getwd(); err == nil; dir = filepath.Dir(dir) {
+		for i := len(instructionFiles) - 1; i >= 0; i-- {
+			if path := filepath.Join(dir, instructionFiles[i]); exists(path) {
+				project = append(project, path)
+			}
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	slices.Reverse(project)
+
+	var files []string
+	if cfg.AppDir != "" {
+		if global := filepath.Join(cfg.AppDir, "instructions.md"); exists(global) {
+			files = append(files, global)
+		}
+	}
+	return append(files, project...)
+}
+
+// System composes the system prompt in layers: the configured prompt, the
+// instruction files, the per-run additions, and the environment the model
+// runs in, including its tools.
+func System(config cfg.Config, toolNames []string) (string, error) {
+	layers := []string{strings.TrimSpace(config.SysPrompt)}
+	for _, path := range InstructionFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("instructions: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			layers = append(layers, "# Instructions: "+path+"\n\n"+text)
+		}
+	}
+	for _, addition := range config.Additions {
+		layers = append(layers, strings.TrimSpace(addition))
+	}
+	layers = append(layers, environment(toolNames))
+
+	layers = slices.DeleteFunc(layers, func(layer string) bool { return layer == "" })
+	return strings.Join(layers, "\n\n"), nil
+}
+
+// environmentHeading starts the environment layer, the last of the prompt.
+const environmentHeading = "# Environment\n"
+
+// WithoutEnvironment returns a system prompt without its environment layer,
+// which changes with the directory and the date.
+func WithoutEnvironment(prompt string) string {
+	if strings.HasPrefix(prompt, environmentHeading) {
+		return ""
+	}
+	if i := strings.LastIndex(prompt, "\n\n"+environmentHeading); i >= 0 {
+		return prompt[:i]
+	}
+	return prompt
+}
+
+// environment describes the environment the model runs in.
+func environment(toolNames []string) string {
+	cwd, _ := os.Getwd()
+	lines := []string{
+		strings.TrimSuffix(environmentHeading, "\n"),
+		"",
+		"- OS: " + runtime.GOOS + "/" + runtime.GOARCH,
+		"- Shell: " + tools.ResolveShell("auto"),
+		"- Working directory: " + cwd,
+		"- Date: " + time.Now().Format("2006-01-02 (Monday)"),
+	}
+	if len(toolNames) > 0 {
+		lines = append(lines, "- Tools: "+strings.Join(toolNames, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBounds are the histogram buckets of durations, in seconds.
+var durationBounds = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// instrument is a counter or histogram, with a point per attribute set.
+type instrument struct {
+	name, unit, description string
+	histogram               bool
+	points                  map[string]*point
+	order                   []string // Keys of points, in creation order
+}
+
+// point is the aggregate of an instrument's measurements with the same
+// attributes.
+type point struct {
+	attrs   []Attribute
+	count   int64
+	sum     float64
+	buckets []int64
+}
+
+// meter aggregates measurements of its instruments from a start time.
+type meter struct {
+	start       time.Time
+	instruments []*instrument
+	mu          sync.Mutex
+}
+
+// counter adds an instrument that sums values.
+func (m *meter) counter(name, unit, description string) *instrument {
+	return m.add(&instrument{name: name, unit: unit, description: description})
+}
+
+// histogram adds an instrument of the distribution of durations.
+func (m *meter) histogram(name, description string) *instrument {
+	return m.add(&instrument{name: name, unit: "s", description: description, histogram: true})
+}
+
+func (m *meter) add(inst *instrument) *instrument {
+	inst.points = map[string]*point{}
+	m.instruments = append(m.instruments, inst)
+	return inst
+}
+
+// record adds a measurement to an instrument.
+func (m *meter) record(inst *instrument, value float64, attrs ...Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := attrsKey(attrs)
+	p, ok := inst.points[key]
+	if !ok {
+		p = &point{attrs: attrs}
+		if inst.histogram {
+			p.buckets = make([]int64, len(durationBounds)+1)
+		}
+		inst.points[key] = p
+		inst.order = append(inst.order, key)
+	}
+
+	p.count++
+	p.sum += value
+	if inst.histogram {
+		bucket, _ := slices.BinarySearch(durationBounds, value)
+		p.buckets[bucket]++
+	}
+}
+
+// payload returns the instruments' cumulative points as an OTLP payload.
+func (m *meter) payload(res resource, sc scope) metricsPayload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start, now := nanos(m.start), nanos(time.Now())
+	var metrics []metricData
+	for _, inst := range m.instruments {
+		if len(inst.order) == 0 {
+			continue
+		}
+
+		data := metricData{Name: inst.name, Unit: inst.unit, Description: inst.description}
+		if inst.histogram {
+			data.Histogram = &histoData{Temporality: cumulative}
+		} else {
+			data.Sum = &sumData{Temporality: cumulative, Monotonic: true}
+		}
+		for _, key := range inst.order {
+			p := inst.points[key]
+			if inst.histogram {
+				buckets := make([]string, len(p.buckets))
+				for i, count := range p.buckets {
+					buckets[i] = strconv.FormatInt(count, 10)
+				}
+				data.Histogram.DataPoints = append(data.Histogram.DataPoints, histoPoint{
+					Attributes: p.attrs, Start: start, Time: now,
+					Count: strconv.FormatInt(p.count, 10), Sum: p.sum,
+					Buckets: buckets, Bounds: durationBounds,
+				})
+			} else {
+				data.Sum.DataPoints = append(data.Sum.DataPoints, numberPoint{
+					Attributes: p.attrs, Start: start, Time: now,
+					Value: strconv.FormatInt(int64(p.sum), 10),
+				})
+			}
+		}
+		metrics = append(metrics, data)
+	}
+
+	return metricsPayload{ResourceMetrics: []resourceMetrics{{
+		Resource:     res,
+		ScopeMetrics: []scopeMetrics{{Scope: sc, Metrics: metrics}},
+	}}}
+}
+
+// attrsKey returns a key identifying a set of attributes.
+func attrsKey(attrs []Attribute) string {
+	var key strings.Builder
+	for _, attr := range attrs {
+		key.WriteString(attr.Key)
+		key.WriteByte('=')
+		switch v := attr.Value; {
+		case v.String != nil:
+			key.WriteString(*v.String)
+		case v.Int != nil:
+			key.WriteString(*v.Int)
+		case v.Bool != nil:
+			key.WriteString(strconv.FormatBool(*v.Bool))
+		}
+		key.WriteByte(0)
+	}
+	return key.String()
+}
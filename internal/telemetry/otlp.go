@@ -0,0 +1,269 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signals exported by telemetry.
+const (
+	Traces  = "traces"
+	Metrics = "metrics"
+)
+
+// Exporter exports OTLP payloads of a signal: traces or metrics.
+type Exporter interface {
+	Export(ctx context.Context, signal string, payload any) error
+}
+
+// MARK: OTLP/HTTP
+// ============================================================================
+
+// OTLP exports payloads to an OTLP/HTTP collector, as JSON.
+type OTLP struct {
+	endpoint string            // Base URL of the collector
+	headers  map[string]string // Request headers, such as authorization
+	client   *http.Client
+}
+
+// NewOTLP creates an exporter to the collector at a base URL, such as
+// http://localhost:4318. Signals are sent to its /v1/traces and /v1/metrics
+// paths, with headers from key=value pairs.
+func NewOTLP(endpoint string, headers []string) (*OTLP, error) {
+	exporter := &OTLP{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  map[string]string{},
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, header := range headers {
+		key, value, ok := strings.Cut(header, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("otlp header: expected key=value, got %q", header)
+		}
+		exporter.headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return exporter, nil
+}
+
+// Export posts the payload to the collector.
+func (e *OTLP) Export(ctx context.Context, signal string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+
+	url := e.endpoint + "/v1/" + signal
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp: %s %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// MARK: File
+// ============================================================================
+
+// File appends payloads to a file as JSON lines, in the format of the
+// OpenTelemetry collector's file exporter.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFile creates an exporter to a file.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Export appends the payload to the file.
+func (e *File) Export(_ context.Context, _ string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("telemetry file: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+		return fmt.Errorf("telemetry file: %w", err)
+	}
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("telemetry file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("telemetry file: %w", err)
+	}
+	return nil
+}
+
+// MARK: OTLP JSON
+// ============================================================================
+
+// Attribute is a key-value pair of a resource, span or data point.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value Value  `json:"value"`
+}
+
+// Value is an attribute value. Exactly one field is set.
+type Value struct {
+	String *string `json:"stringValue,omitempty"`
+	Int    *string `json:"intValue,omitempty"` // int64 as a decimal string
+	Bool   *bool   `json:"boolValue,omitempty"`
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: Value{String: &value}}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int64) Attribute {
+	text := strconv.FormatInt(value, 10)
+	return Attribute{Key: key, Value: Value{Int: &text}}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: Value{Bool: &value}}
+}
+
+// Span kinds and status codes.
+const (
+	kindInternal = 1
+	kindClient   = 3
+
+	statusOK    = 1
+	statusError = 2
+)
+
+type resource struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type tracesPayload struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type spanData struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []Attribute `json:"attributes,omitempty"`
+	Events       []spanEvent `json:"events,omitempty"`
+	Status       *spanStatus `json:"status,omitempty"`
+}
+
+type spanEvent struct {
+	Time       string      `json:"timeUnixNano"`
+	Name       string      `json:"name"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type metricsPayload struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope        `json:"scope"`
+	Metrics []metricData `json:"metrics"`
+}
+
+type metricData struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Sum         *sumData   `json:"sum,omitempty"`
+	Histogram   *histoData `json:"histogram,omitempty"`
+}
+
+// cumulative is the aggregation temporality of exported metrics.
+const cumulative = 2
+
+type sumData struct {
+	DataPoints  []numberPoint `json:"dataPoints"`
+	Temporality int           `json:"aggregationTemporality"`
+	Monotonic   bool          `json:"isMonotonic"`
+}
+
+type numberPoint struct {
+	Attributes []Attribute `json:"attributes,omitempty"`
+	Start      string      `json:"startTimeUnixNano"`
+	Time       string      `json:"timeUnixNano"`
+	Value      string      `json:"asInt"`
+}
+
+type histoData struct {
+	DataPoints  []histoPoint `json:"dataPoints"`
+	Temporality int          `json:"aggregationTemporality"`
+}
+
+type histoPoint struct {
+	Attributes []Attribute `json:"attributes,omitempty"`
+	Start      string      `json:"startTimeUnixNano"`
+	Time       string      `json:"timeUnixNano"`
+	Count      string      `json:"count"`
+	Sum        float64     `json:"sum"`
+	Buckets    []string    `json:"bucketCounts"`
+	Bounds     []float64   `json:"explicitBounds"`
+}
+
+// nanos returns a time as OTLP nanoseconds since the Unix epoch.
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
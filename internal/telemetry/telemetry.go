@@ -0,0 +1,293 @@
+// Package telemetry traces and measures model runs with OpenTelemetry. It
+// builds spans and metrics from model events and exports them, as OTLP
+// JSON, to a collector or a file when each message finishes.
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// Timeout limits how long exporting a run's telemetry takes.
+const Timeout = 10 * time.Second
+
+// Telemetry is an events.Handler that records a span for each message,
+// each request and each tool call, and metrics of requests, tokens, tool
+// calls and errors.
+type Telemetry struct {
+	exporters []Exporter
+	logf      func(format string, args ...any) // Reports failed exports
+	resource  resource
+	scope     scope
+	meter     *meter
+
+	requests, tokens, retries, errors *instrument
+	toolCalls, requestTime, toolTime  *instrument
+
+	run   *span            // Span of the running message
+	turn  *span            // Span of the running request
+	route *events.Route    // Backend of the next request
+	tools map[string]*span // Spans of running tool calls, by call ID
+	spans []spanData       // Finished spans, not yet exported
+	usage events.Usage     // Usage of the running message
+	mu    sync.Mutex
+}
+
+// New creates telemetry of a service and version, exported by exporters.
+// Failed exports are reported with logf.
+func New(service, version string, logf func(format string, args ...any),
+	exporters ...Exporter,
+) *Telemetry {
+	m := &meter{start: time.Now()}
+	return &Telemetry{
+		exporters: exporters,
+		logf:      logf,
+		resource: resource{Attributes: []Attribute{
+			String("service.name", service), String("service.version", version),
+		}},
+		scope: scope{Name: "github.com/mohdfareed/gptx-cli", Version: version},
+		meter: m,
+		tools: map[string]*span{},
+
+		requests:    m.counter("gptx.requests", "{request}", "Requests to models"),
+		tokens:      m.counter("gptx.tokens", "{token}", "Tokens used, by type"),
+		retries:     m.counter("gptx.retries", "{retry}", "Retries of failed requests"),
+		errors:      m.counter("gptx.errors", "{error}", "Failed requests, by kind"),
+		toolCalls:   m.counter("gptx.tool.calls", "{call}", "Tool calls, by tool"),
+		requestTime: m.histogram("gptx.request.duration", "Duration of requests"),
+		toolTime:    m.histogram("gptx.tool.duration", "Duration of tool calls"),
+	}
+}
+
+// MARK: Spans
+// ============================================================================
+
+// span is a running span.
+type span struct {
+	data  spanData
+	start time.Time
+	model string // Requested model, of request spans
+}
+
+// startSpan starts a span, as a child of parent or in a new trace.
+func startSpan(parent *span, name string, kind int, attrs ...Attribute) *span {
+	now := time.Now()
+	s := &span{start: now, data: spanData{
+		SpanID: randomID(8), Name: name, Kind: kind,
+		Start: nanos(now), Attributes: attrs,
+	}}
+	if parent != nil {
+		s.data.TraceID, s.data.ParentSpanID = parent.data.TraceID, parent.data.SpanID
+	} else {
+		s.data.TraceID = randomID(16)
+	}
+	return s
+}
+
+// set adds attributes to the span.
+func (s *span) set(attrs ...Attribute) {
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// event adds an event to the span.
+func (s *span) event(name string, attrs ...Attribute) {
+	s.data.Events = append(s.data.Events, spanEvent{
+		Time: nanos(time.Now()), Name: name, Attributes: attrs,
+	})
+}
+
+// fail marks the span as failed with an error.
+func (s *span) fail(err error) {
+	s.data.Status = &spanStatus{Code: statusError, Message: err.Error()}
+	s.set(String("error.type", string(gptx.ErrorKindOf(err))))
+	s.event("exception", String("exception.message", err.Error()))
+}
+
+// end ends the span, marking it as succeeded unless it failed.
+func (s *span) end() spanData {
+	if s.data.Status == nil {
+		s.data.Status = &spanStatus{Code: statusOK}
+	}
+	s.data.End = nanos(time.Now())
+	return s.data
+}
+
+// randomID returns a random hex ID of n bytes.
+func randomID(n int) string {
+	id := make([]byte, n)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// MARK: Events
+// ============================================================================
+
+// HandleEvent records a model event in spans and metrics, and exports them
+// when a message finishes.
+func (t *Telemetry) HandleEvent(event events.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e := event.(type) {
+	case events.RunStarted:
+		t.run = startSpan(nil, "gptx.message", kindInternal,
+			String("gen_ai.system", e.Config.Provider),
+			String("gen_ai.request.model", e.Config.Model))
+		t.usage = events.Usage{}
+
+	case events.TurnStarted:
+		t.turn = startSpan(t.run, "chat "+e.Config.Model, kindClient,
+			String("gen_ai.operation.name", "chat"),
+			String("gen_ai.system", e.Config.Provider),
+			String("gen_ai.request.model", e.Config.Model))
+		t.turn.model = e.Config.Model
+		if t.route != nil {
+			t.turn.set(String("gptx.route.backend", t.route.Backend),
+				String("gptx.route.reason", t.route.Reason))
+			t.route = nil
+		}
+
+	case events.Route:
+		// Routes are reported once the backend serves the running request
+		if t.turn != nil {
+			t.turn.set(String("gptx.route.backend", e.Backend),
+				String("gptx.route.reason", e.Reason))
+		} else {
+			t.route = &e
+		}
+
+	case events.Failover:
+		// The request restarts on the next backend
+		if t.turn != nil {
+			t.turn.fail(e.Err)
+			t.turn.set(String("gptx.failover.to", e.To))
+			t.spans = append(t.spans, t.turn.end())
+			t.turn = nil
+		}
+
+	case events.Retry:
+		t.meter.record(t.retries, 1, String("error.type", string(gptx.ErrorKindOf(e.Err))))
+		if t.turn != nil {
+			t.turn.event("retry", String("exception.message", e.Err.Error()),
+				Int("gptx.retry.delay_ms", e.Delay.Milliseconds()))
+		}
+
+	case events.WebSearch:
+		if t.turn != nil {
+			t.turn.event("web_search")
+		}
+
+	case events.Usage:
+		t.usage = t.usage.Add(e)
+		var model string
+		if t.turn != nil {
+			model = t.turn.model
+			t.turn.set(usageAttrs(e)...)
+		}
+		for _, tokens := range []struct {
+			kind  string
+			count int
+		}{
+			{"input", e.InputTokens}, {"cached", e.CachedTokens},
+			{"output", e.OutputTokens}, {"reasoning", e.ReasoningTokens},
+		} {
+			if tokens.count > 0 {
+				t.meter.record(t.tokens, float64(tokens.count),
+					String("gen_ai.request.model", model),
+					String("gen_ai.token.type", tokens.kind))
+			}
+		}
+
+	case events.Error:
+		t.meter.record(t.errors, 1, String("error.type", string(gptx.ErrorKindOf(e.Err))))
+		if t.turn != nil {
+			t.turn.fail(e.Err)
+		}
+
+	case events.TurnFinished:
+		if t.turn == nil {
+			return
+		}
+		model := String("gen_ai.request.model", t.turn.model)
+		t.turn.set(String("gen_ai.response.finish_reasons", e.Reason))
+		if e.Reason == events.FinishError && t.turn.data.Status == nil {
+			t.turn.data.Status = &spanStatus{Code: statusError}
+		}
+		t.meter.record(t.requests, 1, model, String("gen_ai.response.finish_reasons", e.Reason))
+		t.meter.record(t.requestTime, time.Since(t.turn.start).Seconds(), model)
+		t.spans = append(t.spans, t.turn.end())
+		t.turn = nil
+
+	case events.ToolCallStarted:
+		parent := t.turn
+		if parent == nil {
+			parent = t.run
+		}
+		t.tools[e.ID] = startSpan(parent, "execute_tool "+e.Name, kindInternal,
+			String("gen_ai.operation.name", "execute_tool"),
+			String("gen_ai.tool.name", e.Name),
+			String("gen_ai.tool.call.id", e.ID))
+
+	case events.ToolCallFinished:
+		tool := String("gen_ai.tool.name", e.Name)
+		t.meter.record(t.toolCalls, 1, tool, Bool("error", e.Err != nil))
+		t.meter.record(t.toolTime, e.Duration.Seconds(), tool)
+		if s, ok := t.tools[e.ID]; ok {
+			if e.Err != nil {
+				s.fail(e.Err)
+			}
+			t.spans = append(t.spans, s.end())
+			delete(t.tools, e.ID)
+		}
+
+	case events.RunFinished:
+		if t.run == nil {
+			return
+		}
+		t.run.set(usageAttrs(t.usage)...)
+		if e.Err != nil {
+			t.run.fail(e.Err)
+		}
+		t.spans = append(t.spans, t.run.end())
+		t.run = nil
+		t.export()
+	}
+}
+
+// usageAttrs returns the attributes of token usage.
+func usageAttrs(usage events.Usage) []Attribute {
+	return []Attribute{
+		Int("gen_ai.usage.input_tokens", int64(usage.InputTokens)),
+		Int("gen_ai.usage.output_tokens", int64(usage.OutputTokens)),
+		Int("gptx.usage.cached_tokens", int64(usage.CachedTokens)),
+		Int("gptx.usage.reasoning_tokens", int64(usage.ReasoningTokens)),
+	}
+}
+
+// export sends the finished spans and the metrics to the exporters.
+func (t *Telemetry) export() {
+	traces := tracesPayload{ResourceSpans: []resourceSpans{{
+		Resource:   t.resource,
+		ScopeSpans: []scopeSpans{{Scope: t.scope, Spans: t.spans}},
+	}}}
+	metrics := t.meter.payload(t.resource, t.scope)
+	t.spans = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	for _, exporter := range t.exporters {
+		if err := exporter.Export(ctx, Traces, traces); err != nil {
+			t.logf("Telemetry export failed: %s", err)
+			continue
+		}
+		if err := exporter.Export(ctx, Metrics, metrics); err != nil {
+			t.logf("Telemetry export failed: %s", err)
+		}
+	}
+}
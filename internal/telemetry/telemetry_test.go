@@ -0,0 +1,264 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+// collector is a stand-in for an OTLP/HTTP collector, keeping the payloads
+// it receives by path.
+type collector struct {
+	*httptest.Server
+	payloads map[string][][]byte
+	headers  http.Header
+	status   int // Response status, 200 if unset
+	mu       sync.Mutex
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{payloads: map[string][][]byte{}}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.payloads[r.URL.Path] = append(c.payloads[r.URL.Path], body)
+		c.headers = r.Header.Clone()
+		if c.status != 0 {
+			http.Error(w, "unavailable", c.status)
+		}
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// decode decodes the last payload received on a path.
+func (c *collector) decode(t *testing.T, path string, payload any) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	received := c.payloads[path]
+	if len(received) == 0 {
+		t.Fatalf("collector received nothing on %s", path)
+	}
+	if err := json.Unmarshal(received[len(received)-1], payload); err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+}
+
+// runMessage emits the events of a message with a tool call between two
+// requests, the first of which is retried.
+func runMessage(tel *Telemetry) {
+	config := cfg.Config{Provider: "openai", Model: "gpt-4.1"}
+	for _, event := range []events.Event{
+		events.RunStarted{Config: config},
+		events.TurnStarted{Config: config},
+		events.Retry{Err: errors.New("rate limited"), Delay: time.Second},
+		events.TextDelta{Text: "Listing."},
+		events.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+		events.TurnFinished{Reason: events.FinishToolCalls},
+		events.ToolCallStarted{ID: "call_1", Name: "shell", Args: `{"cmd":"ls"}`},
+		events.ToolCallFinished{ID: "call_1", Name: "shell", Result: "main.go", Duration: 3 * time.Second},
+		events.TurnStarted{Config: config},
+		events.Usage{InputTokens: 20, OutputTokens: 4, TotalTokens: 24},
+		events.TurnFinished{Reason: events.FinishCompleted},
+		events.RunFinished{},
+	} {
+		tel.HandleEvent(event)
+	}
+}
+
+func TestExportOTLP(t *testing.T) {
+	col := newCollector(t)
+	exporter, err := NewOTLP(col.URL+"/", []string{"Authorization = Bearer token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []string
+	tel := New("gptx", "v1.0.0", func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}, exporter)
+	runMessage(tel)
+
+	if len(failures) > 0 {
+		t.Fatalf("export failed: %v", failures)
+	}
+	if got := col.headers.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+	if got := col.headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var traces tracesPayload
+	col.decode(t, "/v1/traces", &traces)
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	byName := map[string][]spanData{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if len(spans) != 4 || len(byName["gptx.message"]) != 1 ||
+		len(byName["chat gpt-4.1"]) != 2 || len(byName["execute_tool shell"]) != 1 {
+		t.Fatalf("spans = %+v, want a message, two requests and a tool call", spans)
+	}
+
+	// Spans share the message's trace, which is their parent
+	message := byName["gptx.message"][0]
+	traceID, spanID := regexp.MustCompile(`^[0-9a-f]{32}$`), regexp.MustCompile(`^[0-9a-f]{16}$`)
+	for _, span := range spans {
+		if !traceID.MatchString(span.TraceID) || !spanID.MatchString(span.SpanID) {
+			t.Errorf("span %s has IDs %q/%q, want 16 and 8 hex bytes", span.Name, span.TraceID, span.SpanID)
+		}
+		if span.TraceID != message.TraceID {
+			t.Errorf("span %s is in trace %s, want %s", span.Name, span.TraceID, message.TraceID)
+		}
+		if span.Name != message.Name && span.ParentSpanID != message.SpanID {
+			t.Errorf("span %s has parent %q, want the message span", span.Name, span.ParentSpanID)
+		}
+		if span.Status == nil || span.Status.Code != statusOK {
+			t.Errorf("span %s has status %+v, want ok", span.Name, span.Status)
+		}
+	}
+	if message.ParentSpanID != "" {
+		t.Errorf("message span has parent %q, want none", message.ParentSpanID)
+	}
+
+	// Requests end before their tool calls run
+	first, tool := byName["chat gpt-4.1"][0], byName["execute_tool shell"][0]
+	if first.End > tool.Start {
+		t.Errorf("request ended at %s, after its tool call started at %s", first.End, tool.Start)
+	}
+	if first.Kind != kindClient || tool.Kind != kindInternal {
+		t.Errorf("kinds = %d, %d, want client and internal", first.Kind, tool.Kind)
+	}
+	if len(first.Events) != 1 || first.Events[0].Name != "retry" {
+		t.Errorf("request events = %+v, want a retry", first.Events)
+	}
+	if got := attr(first.Attributes, "gen_ai.usage.input_tokens"); got != "10" {
+		t.Errorf("request input tokens = %q, want 10", got)
+	}
+	if got := attr(message.Attributes, "gen_ai.usage.input_tokens"); got != "30" {
+		t.Errorf("message input tokens = %q, want 30", got)
+	}
+
+	var metrics metricsPayload
+	col.decode(t, "/v1/metrics", &metrics)
+	byMetric := map[string]metricData{}
+	for _, metric := range metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		byMetric[metric.Name] = metric
+	}
+	requests := byMetric["gptx.requests"].Sum
+	if requests == nil || requests.Temporality != cumulative || !requests.Monotonic {
+		t.Fatalf("gptx.requests = %+v, want a cumulative monotonic sum", byMetric["gptx.requests"])
+	}
+	tokens := map[string]string{}
+	for _, p := range byMetric["gptx.tokens"].Sum.DataPoints {
+		tokens[attr(p.Attributes, "gen_ai.token.type")] = p.Value
+	}
+	if tokens["input"] != "30" || tokens["output"] != "9" {
+		t.Errorf("tokens = %v, want 30 input and 9 output", tokens)
+	}
+	if got := byMetric["gptx.retries"].Sum.DataPoints[0].Value; got != "1" {
+		t.Errorf("retries = %s, want 1", got)
+	}
+
+	toolTime := byMetric["gptx.tool.duration"].Histogram
+	if toolTime == nil || toolTime.Temporality != cumulative {
+		t.Fatalf("gptx.tool.duration = %+v, want a cumulative histogram", byMetric["gptx.tool.duration"])
+	}
+	if p := toolTime.DataPoints[0]; p.Count != "1" || p.Sum != 3 || p.Buckets[5] != "1" {
+		t.Errorf("tool duration point = %+v, want 3s in the (2.5, 5] bucket", p)
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	col := newCollector(t)
+	col.status = http.StatusServiceUnavailable
+	exporter, err := NewOTLP(col.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []string
+	tel := New("gptx", "v1.0.0", func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}, exporter)
+	runMessage(tel)
+
+	// Metrics aren't sent once traces fail
+	if len(failures) != 1 || !strings.Contains(failures[0], "503") {
+		t.Errorf("failures = %q, want one 503 failure", failures)
+	}
+}
+
+func TestOTLPHeaders(t *testing.T) {
+	if _, err := NewOTLP("http://localhost:4318", []string{"no-value"}); err == nil {
+		t.Error("NewOTLP() accepted a header without a value")
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	m := &meter{start: time.Now()}
+	hist := m.histogram("duration", "")
+	for _, seconds := range []float64{0.05, 0.1, 0.2, 60, 61, 500} {
+		m.record(hist, seconds)
+	}
+
+	metric := m.payload(resource{}, scope{}).ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	p := metric.Histogram.DataPoints[0]
+	// Buckets include their upper bound, and the last is unbounded
+	want := []string{"2", "1", "0", "0", "0", "0", "0", "0", "1", "1", "1"}
+	if strings.Join(p.Buckets, ",") != strings.Join(want, ",") {
+		t.Errorf("buckets = %v, want %v", p.Buckets, want)
+	}
+	if len(p.Bounds) != len(p.Buckets)-1 {
+		t.Errorf("%d bounds for %d buckets, want one fewer", len(p.Bounds), len(p.Buckets))
+	}
+	if p.Count != "6" || p.Sum != 621.35 {
+		t.Errorf("count = %s, sum = %g, want 6 and 621.35", p.Count, p.Sum)
+	}
+}
+
+func TestAttributesJSON(t *testing.T) {
+	data, err := json.Marshal([]Attribute{
+		String("s", "v"), Int("i", 1<<53+1), Bool("b", false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Integers are strings in OTLP JSON, so they keep 64-bit precision
+	want := `[{"key":"s","value":{"stringValue":"v"}},` +
+		`{"key":"i","value":{"intValue":"9007199254740993"}},` +
+		`{"key":"b","value":{"boolValue":false}}]`
+	if string(data) != want {
+		t.Errorf("attributes = %s, want %s", data, want)
+	}
+}
+
+// attr returns the value of an attribute as text.
+func attr(attrs []Attribute, key string) string {
+	for _, a := range attrs {
+		if a.Key != key {
+			continue
+		}
+		switch v := a.Value; {
+		case v.String != nil:
+			return *v.String
+		case v.Int != nil:
+			return *v.Int
+		case v.Bool != nil:
+			return fmt.Sprint(*v.Bool)
+		}
+	}
+	return ""
+}
@@ -20,6 +20,26 @@ type ToolDef struct {
 	Handler  Tool           // Implementation
 }
 
+// Validate checks that the tool definition is complete and that its
+// parameters can be sent to a model.
+func (d ToolDef) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("tool: missing name")
+	}
+	if d.Handler == nil {
+		return fmt.Errorf("tool %s: missing handler", d.Name)
+	}
+	for _, param := range d.Required {
+		if _, ok := d.Params[param]; !ok {
+			return fmt.Errorf("tool %s: unknown required parameter %q", d.Name, param)
+		}
+	}
+	if _, err := json.Marshal(d.Params); err != nil {
+		return fmt.Errorf("tool %s: parameters: %w", d.Name, err)
+	}
+	return nil
+}
+
 // ToolCall represents a request from the model to use a tool.
 type ToolCall struct {
 	Name   string // Tool name
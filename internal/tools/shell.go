@@ -23,10 +23,7 @@ Use this for file operations, system information, or any command-line tasks.
 // NewShellTool creates a shell tool from the given config.
 func NewShellTool(config cfg.Config) ToolDef {
 	// Determine which shell to use
-	shell := getDefaultShell()
-	if config.Shell != "auto" {
-		shell = config.Shell
-	}
+	shell := ResolveShell(config.Shell)
 
 	// Create the tool definition
 	return ToolDef{
@@ -50,6 +47,15 @@ func NewShellTool(config cfg.Config) ToolDef {
 	}
 }
 
+// ResolveShell returns the shell to run commands with, where "auto"
+// selects the user's default shell.
+func ResolveShell(shell string) string {
+	if shell == "auto" {
+		return getDefaultShell()
+	}
+	return shell
+}
+
 // shellHandler implements the shell tool functionality.
 // It executes a shell command and returns the output or an error.
 //
@@ -0,0 +1,148 @@
+// Package cassette records HTTP exchanges with model providers into
+// cassette files and replays them, so that requests, including streamed
+// responses, can be served offline and deterministically.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Cassette is a recording of HTTP exchanges, in order.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response is a recorded HTTP response. Streamed responses are recorded
+// whole, with their events.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Redacted replaces scrubbed values in cassettes.
+const Redacted = "[REDACTED]"
+
+// secretHeaders are headers that are always scrubbed.
+var secretHeaders = []string{
+	"Authorization", "Api-Key", "X-Api-Key", "Cookie", "Set-Cookie",
+	"Openai-Organization", "Openai-Project",
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette %q: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// MARK: Scrubbing
+// ============================================================================
+
+// Scrubber removes secrets from recorded interactions.
+type Scrubber struct {
+	secrets  []string         // Literal values, such as API keys
+	patterns []*regexp.Regexp // Patterns of sensitive values
+}
+
+// NewScrubber creates a scrubber of literal secrets and regular expression
+// patterns. Secret headers, such as Authorization, are always scrubbed.
+func NewScrubber(secrets []string, patterns []string) (*Scrubber, error) {
+	scrubber := &Scrubber{}
+	for _, secret := range secrets {
+		if secret != "" {
+			scrubber.secrets = append(scrubber.secrets, secret)
+		}
+	}
+
+	var errs []error
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scrub pattern %q: %w", pattern, err))
+			continue
+		}
+		scrubber.patterns = append(scrubber.patterns, re)
+	}
+	return scrubber, errors.Join(errs...)
+}
+
+// Scrub returns the interaction with its secrets redacted.
+func (s *Scrubber) Scrub(interaction Interaction) Interaction {
+	interaction.Request.URL = s.scrub(interaction.Request.URL)
+	interaction.Request.Header = s.scrubHeader(interaction.Request.Header)
+	interaction.Request.Body = s.scrub(interaction.Request.Body)
+	interaction.Response.Header = s.scrubHeader(interaction.Response.Header)
+	interaction.Response.Body = s.scrub(interaction.Response.Body)
+	return interaction
+}
+
+// scrub redacts secrets in text.
+func (s *Scrubber) scrub(text string) string {
+	for _, secret := range s.secrets {
+		text = strings.ReplaceAll(text, secret, Redacted)
+	}
+	for _, re := range s.patterns {
+		text = re.ReplaceAllString(text, Redacted)
+	}
+	return text
+}
+
+// scrubHeader returns a copy of header with secrets redacted.
+func (s *Scrubber) scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			scrubbed.Add(name, s.scrub(value))
+		}
+	}
+	for _, name := range secretHeaders {
+		if scrubbed.Get(name) != "" {
+			scrubbed.Set(name, Redacted)
+		}
+	}
+	return scrubbed
+}
@@ -0,0 +1,208 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// MARK: Recorder
+// ============================================================================
+
+// Recorder is a transport that records the exchanges it sends into a
+// cassette file, which is saved after each response is read.
+type Recorder struct {
+	path      string                           // Cassette file
+	transport http.RoundTripper                // Transport that sends the requests
+	scrubber  *Scrubber                        // Scrubs secrets before saving
+	cassette  Cassette                         // Recorded interactions
+	logf      func(format string, args ...any) // Reports failed saves
+	mu        sync.Mutex                       // Protects the cassette
+}
+
+// NewRecorder creates a recorder that sends requests with transport and
+// records them into a new cassette at path. A nil transport uses
+// http.DefaultTransport and a nil scrubber only scrubs secret headers.
+// Failed saves are reported with logf.
+func NewRecorder(
+	path string, transport http.RoundTripper, scrubber *Scrubber,
+	logf func(format string, args ...any),
+) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if scrubber == nil {
+		scrubber = &Scrubber{}
+	}
+	return &Recorder{path: path, transport: transport, scrubber: scrubber, logf: logf}
+}
+
+// RoundTrip sends the request and records it with its response. The
+// response is passed through as it streams and recorded once read.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{
+		Method: req.Method, URL: req.URL.String(),
+		Header: req.Header.Clone(), Body: body,
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &teeBody{body: resp.Body, done: func(data []byte) {
+		r.record(Interaction{Request: recorded, Response: Response{
+			Status: resp.StatusCode, Header: resp.Header.Clone(), Body: string(data),
+		}})
+	}}
+	return resp, nil
+}
+
+// record adds a scrubbed interaction to the cassette and saves it.
+func (r *Recorder) record(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, r.scrubber.Scrub(interaction))
+	if err := r.cassette.Save(r.path); err != nil {
+		r.logf("Recording failed: %s", err)
+	}
+}
+
+// teeBody copies a response body as it is read, calling done with the
+// data read once the body ends or is closed.
+type teeBody struct {
+	body     io.ReadCloser
+	data     bytes.Buffer
+	done     func([]byte)
+	finished bool
+}
+
+// Read reads from the body, recording the data.
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	t.data.Write(p[:n])
+	if err == io.EOF {
+		t.finish()
+	}
+	return n, err
+}
+
+// Close closes the body, recording the data read so far.
+func (t *teeBody) Close() error {
+	t.finish()
+	return t.body.Close()
+}
+
+// finish records the data once.
+func (t *teeBody) finish() {
+	if !t.finished {
+		t.finished = true
+		t.done(t.data.Bytes())
+	}
+}
+
+// MARK: Replayer
+// ============================================================================
+
+// Replayer is a transport that serves responses from a cassette instead of
+// sending requests.
+type Replayer struct {
+	cassette *Cassette // Recorded interactions
+	used     []bool    // Interactions already replayed
+	mu       sync.Mutex
+}
+
+// NewReplayer creates a replayer of a cassette file.
+func NewReplayer(path string) (*Replayer, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		cassette: cassette, used: make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// RoundTrip serves the recorded response of the first unused interaction
+// with the request's method and URL, preferring one with the same body.
+// Bodies may differ, since requests include details such as the date.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method || !sameURL(recorded.URL, req.URL) {
+			continue
+		}
+		if recorded.Body == body {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("cassette: no recorded response for %s %s", req.Method, req.URL)
+	}
+	r.used[match] = true
+
+	recorded := r.cassette.Interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// MARK: Helpers
+// ============================================================================
+
+// readBody reads a request's body and restores it for sending.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("cassette: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+// sameURL reports whether a recorded URL matches a request URL by path and
+// query, so that cassettes replay against any host. Recorded URLs may have
+// scrubbed parts, which match anything.
+func sameURL(recorded string, request *url.URL) bool {
+	parsed, err := url.Parse(recorded)
+	if err != nil {
+		return false
+	}
+	recordedURI, requestURI := parsed.RequestURI(), request.RequestURI()
+	if recordedURI == requestURI {
+		return true
+	}
+	before, after, ok := strings.Cut(recordedURI, url.PathEscape(Redacted))
+	return ok && strings.HasPrefix(requestURI, before) && strings.HasSuffix(requestURI, after)
+}
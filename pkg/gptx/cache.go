@@ -0,0 +1,438 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+// Cache stores model responses on disk, one file per request, so that
+// identical requests are answered without calling the model again.
+type Cache struct {
+	dir       string                // Directory of entry files
+	ttl       time.Duration         // Entry lifetime, zero for no expiry
+	maxSize   int64                 // Total size limit in bytes, zero for no limit
+	normalize func(Request) Request // Normalizes requests before hashing
+}
+
+// CacheOption is a function that configures a Cache.
+type CacheOption func(*Cache)
+
+// CacheEntry is a stored response and the events streamed while it was
+// generated.
+type CacheEntry struct {
+	Key      string       `json:"key"`      // Request hash
+	Model    string       `json:"model"`    // Requested model
+	Created  time.Time    `json:"created"`  // Time the response was stored
+	Used     time.Time    `json:"used"`     // Last time the entry was replayed
+	Hits     int          `json:"hits"`     // Number of replays
+	Events   []CacheEvent `json:"events"`   // Streamed events, in order
+	Response Response     `json:"response"` // Final response
+}
+
+// CacheEvent is a streamed event of a cached response.
+type CacheEvent struct {
+	Type string `json:"type"` // reply or reasoning
+	Text string `json:"text"` // Event text
+}
+
+// CacheStats summarizes the contents of a cache.
+type CacheStats struct {
+	Dir     string    // Cache directory
+	Entries int       // Number of entries
+	Expired int       // Entries past their lifetime
+	Size    int64     // Total size in bytes
+	Hits    int       // Replays across all entries
+	Oldest  time.Time // Creation time of the oldest entry
+}
+
+// NewCache creates a cache in dir. Entries expire after ttl and the least
+// recently used entries are evicted once the cache exceeds maxSize bytes.
+// Zero values disable expiry and eviction.
+func NewCache(dir string, ttl time.Duration, maxSize int64, options ...CacheOption) *Cache {
+	cache := &Cache{dir: dir, ttl: ttl, maxSize: maxSize}
+	for _, option := range options {
+		option(cache)
+	}
+	return cache
+}
+
+// WithKeyNormalizer normalizes requests before they are hashed into keys,
+// such as to drop the parts of the instructions that vary between runs.
+func WithKeyNormalizer(normalize func(Request) Request) CacheOption {
+	return func(c *Cache) {
+		c.normalize = normalize
+	}
+}
+
+// Key returns the key of a request, normalized with the cache's normalizer.
+func (c *Cache) Key(request Request) (string, error) {
+	if c.normalize != nil {
+		request = c.normalize(request)
+	}
+	return RequestKey(request)
+}
+
+// Lookup returns the entry stored under a key, if it has not expired, and
+// records the hit. Expired entries are removed.
+func (c *Cache) Lookup(key string) (CacheEntry, bool) {
+	path := c.path(key)
+	entry, err := readCacheEntry(path)
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	if c.expired(entry) {
+		os.Remove(path)
+		return CacheEntry{}, false
+	}
+
+	// Rewriting the entry also marks it as recently used for eviction
+	entry.Used = time.Now()
+	entry.Hits++
+	_ = c.write(entry)
+	return entry, true
+}
+
+// Store saves an entry and evicts entries beyond the cache's size limit.
+func (c *Cache) Store(entry CacheEntry) error {
+	now := time.Now()
+	entry.Created, entry.Used = now, now
+	if err := c.write(entry); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// Stats summarizes the entries in the cache.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.dir}
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, file := range files {
+		entry, err := readCacheEntry(file.path)
+		if err != nil {
+			continue // removed by another process or corrupt
+		}
+		stats.Entries++
+		stats.Size += file.size
+		stats.Hits += entry.Hits
+		if c.expired(entry) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || entry.Created.Before(stats.Oldest) {
+			stats.Oldest = entry.Created
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every entry and returns the number removed.
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range files {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return i, fmt.Errorf("cache: %w", err)
+		}
+	}
+	return len(files), nil
+}
+
+// MARK: Storage
+// ============================================================================
+
+// cacheFile is an entry file and its size and modification time, which is
+// the last time the entry was used.
+type cacheFile struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// path returns the entry file of a key.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// expired reports whether an entry is past its lifetime.
+func (c *Cache) expired(entry CacheEntry) bool {
+	return c.ttl > 0 && time.Since(entry.Created) > c.ttl
+}
+
+// write saves an entry atomically, so concurrent runs never read a
+// partial entry.
+func (c *Cache) write(entry CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, entry.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(entry.Key)); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// evict removes entries that have not been used within their lifetime,
+// then the least recently used entries until the cache fits its limit.
+func (c *Cache) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(files, func(a, b cacheFile) int {
+		return a.used.Compare(b.used)
+	})
+
+	var size int64
+	for _, file := range files {
+		size += file.size
+	}
+	for _, file := range files {
+		stale := c.ttl > 0 && time.Since(file.used) > c.ttl
+		if !stale && (c.maxSize <= 0 || size <= c.maxSize) {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cache: %w", err)
+		}
+		size -= file.size
+	}
+	return nil
+}
+
+// files lists the cache's entry files.
+func (c *Cache) files() ([]cacheFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+
+	var files []cacheFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue // removed by another process
+		}
+		files = append(files, cacheFile{
+			path: filepath.Join(c.dir, dirEntry.Name()),
+			size: info.Size(), used: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// readCacheEntry reads an entry file.
+func readCacheEntry(path string) (CacheEntry, error) {
+	var entry CacheEntry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// MARK: Request Keys
+// ============================================================================
+
+// cacheRequest is the normalized content of a request that determines its
+// response. Callbacks and handlers are left out, and attached files are
+// identified by content rather than path.
+type cacheRequest struct {
+	Provider     string         `json:"provider"`
+	BaseURL      string         `json:"base_url"`
+	Model        string         `json:"model"`
+	Instructions string         `json:"instructions"`
+	Messages     []cacheMessage `json:"messages"`
+	Tools        []cacheTool    `json:"tools"`
+	WebSearch    bool           `json:"web_search"`
+	Schema       *Schema        `json:"schema"`
+	Params       Params         `json:"params"`
+}
+
+// cacheMessage is a normalized message.
+type cacheMessage struct {
+	Role     string       `json:"role"`
+	Content  string       `json:"content"`
+	Name     string       `json:"name"`
+	ToolCall string       `json:"tool_call,omitempty"` // Tool name and arguments
+	Files    []string     `json:"files"`               // File names and content hashes
+	Context  []Attachment `json:"context"`
+	Parts    []Part       `json:"parts,omitempty"` // Attachments as sent
+}
+
+// cacheTool is a normalized tool definition.
+type cacheTool struct {
+	Name     string         `json:"name"`
+	Desc     string         `json:"desc"`
+	Params   map[string]any `json:"params"`
+	Required []string       `json:"required"`
+}
+
+// RequestKey hashes the content of a request that determines its response:
+// the model, instructions, messages and their attachments, tools and
+// request parameters.
+func RequestKey(request Request) (string, error) {
+	config := request.Config
+	normalized := cacheRequest{
+		Provider: config.Provider, BaseURL: config.BaseURL,
+		Model: config.Model, Instructions: strings.TrimSpace(config.SysPrompt),
+		WebSearch: config.WebSearch, Schema: request.Schema,
+		Params: RequestParams(config),
+	}
+
+	for _, msg := range request.Messages {
+		normalizedMsg := cacheMessage{
+			Role: msg.Role, Content: msg.Content, Name: msg.Name,
+			Context: msg.Context,
+		}
+		if msg.ToolCall != nil { // call IDs vary between identical responses
+			normalizedMsg.ToolCall = msg.ToolCall.Name + msg.ToolCall.Arguments
+		}
+		if len(msg.Parts) > 0 { // sent as converted, without the files
+			normalizedMsg.Context, normalizedMsg.Parts = nil, msg.Parts
+			normalized.Messages = append(normalized.Messages, normalizedMsg)
+			continue
+		}
+		for _, path := range msg.Files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("cache key: %w", err)
+			}
+			sum := sha256.Sum256(data)
+			normalizedMsg.Files = append(normalizedMsg.Files,
+				filepath.Base(path)+":"+hex.EncodeToString(sum[:]))
+		}
+		normalized.Messages = append(normalized.Messages, normalizedMsg)
+	}
+
+	for _, def := range request.ToolDefs {
+		normalized.Tools = append(normalized.Tools, cacheTool{
+			Name: def.Name, Desc: def.Desc,
+			Params: def.Params, Required: def.Required,
+		})
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// MARK: Client
+// ============================================================================
+
+// cachedClient answers requests from a cache before calling its client.
+type cachedClient struct {
+	client Client // Client called on cache misses
+	cache  *Cache // Response cache
+}
+
+// Cached is a middleware that answers requests from a response cache.
+// Hits replay the stored events through the request's event handler. Responses
+// with tool calls are not stored, since the tools must run again.
+func Cached(cache *Cache) Middleware {
+	return func(client Client) Client {
+		return &cachedClient{client: client, cache: cache}
+	}
+}
+
+// SendRequest returns the cached response for the request, if any, or
+// sends it and stores the response.
+func (c *cachedClient) SendRequest(ctx context.Context, request Request) (Response, error) {
+	key, err := c.cache.Key(request)
+	if err != nil {
+		return c.client.SendRequest(ctx, request) // uncacheable request
+	}
+	if entry, ok := c.cache.Lookup(key); ok {
+		return replay(entry, request), nil
+	}
+
+	// Record the streamed events while passing them through
+	var recorded []CacheEvent
+	attempt := request
+	attempt.Events = events.HandlerFunc(func(event events.Event) {
+		switch e := event.(type) {
+		case events.TextDelta:
+			recorded = append(recorded, CacheEvent{Type: "reply", Text: e.Text})
+		case events.ReasoningDelta:
+			recorded = append(recorded, CacheEvent{Type: "reasoning", Text: e.Text})
+		}
+		request.Emit(event)
+	})
+
+	response, err := c.client.SendRequest(ctx, attempt)
+	if err != nil || response.HasToolCalls {
+		return response, err
+	}
+
+	// Failing to store the response doesn't fail the request
+	entry := CacheEntry{
+		Key: key, Model: request.Config.Model,
+		Events: recorded, Response: response,
+	}
+	if err := c.cache.Store(entry); err != nil {
+		request.Emit(events.Error{Err: err})
+	}
+	return response, nil
+}
+
+// replay streams a cached entry through the request's event handler and
+// returns its response. Replays use no tokens, so their usage is zero.
+func replay(entry CacheEntry, request Request) Response {
+	request.Emit(events.TurnStarted{Config: request.Config})
+	for _, event := range entry.Events {
+		switch event.Type {
+		case "reply":
+			request.Emit(events.TextDelta{Text: event.Text})
+		case "reasoning":
+			request.Emit(events.ReasoningDelta{Text: event.Text})
+		}
+	}
+
+	var usage events.Usage
+	request.Emit(usage)
+	request.Emit(events.TurnFinished{Reason: events.FinishCompleted})
+	response := entry.Response
+	response.Usage = usage.String()
+	return response
+}
@@ -0,0 +1,205 @@
+package gptx_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx/fake"
+)
+
+func TestRequestKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	request := func(prompt string, call string, files ...string) gptx.Request {
+		messages := []gptx.Message{{Role: "user", Content: "hi", Files: files}}
+		if call != "" {
+			messages = append(messages, gptx.Message{
+				Role: "assistant", ToolCall: &gptx.ToolCall{ID: call, Name: "ls", Arguments: "{}"},
+			})
+		}
+		return gptx.Request{Config: cfg.Config{Model: "gpt-4.1", SysPrompt: prompt}, Messages: messages}
+	}
+	key := func(request gptx.Request) string {
+		t.Helper()
+		key, err := gptx.RequestKey(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	a := write("a.txt", "same")
+	b := write("sub.txt", "same")
+	c := write("c.txt", "other")
+	base := key(request("Be brief.", "call_1", a))
+
+	if key(request("Be brief.", "call_2", a)) != base {
+		t.Error("tool call IDs changed the key")
+	}
+	if key(request(" Be brief.\n", "call_1", a)) != base {
+		t.Error("surrounding whitespace of the instructions changed the key")
+	}
+	if key(request("Be brief.", "call_1", c)) == base {
+		t.Error("file contents didn't change the key")
+	}
+	if key(request("Be terse.", "call_1", a)) == base {
+		t.Error("instructions didn't change the key")
+	}
+
+	// Files are identified by name and content, not directory
+	moved := filepath.Join(dir, "moved")
+	if err := os.Mkdir(moved, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(b, filepath.Join(moved, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if key(request("Be brief.", "call_1", filepath.Join(moved, "a.txt"))) != base {
+		t.Error("the file's directory changed the key")
+	}
+
+	// Normalizers apply to the cache's keys only
+	cache := gptx.NewCache(dir, 0, 0, gptx.WithKeyNormalizer(func(r gptx.Request) gptx.Request {
+		r.Config.SysPrompt, _, _ = strings.Cut(r.Config.SysPrompt, "\n\n# Env")
+		return r
+	}))
+	withEnv, err := cache.Key(request("Be brief.\n\n# Env\n\ndate", "call_1", a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withEnv != base {
+		t.Error("normalized instructions changed the key")
+	}
+	if key(request("Be brief.\n\n# Env\n\ndate", "call_1", a)) == base {
+		t.Error("RequestKey normalized the instructions without a normalizer")
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	cache := gptx.NewCache(dir, time.Hour, 0)
+	if err := cache.Store(gptx.CacheEntry{Key: "fresh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are stored with their creation time
+	stale, _ := json.Marshal(gptx.CacheEntry{Key: "stale", Created: time.Now().Add(-2 * time.Hour)})
+	if err := os.WriteFile(filepath.Join(dir, "stale.json"), stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil || stats.Entries != 2 || stats.Expired != 1 {
+		t.Errorf("Stats() = %+v, %v, want 2 entries with 1 expired", stats, err)
+	}
+	if entry, ok := cache.Lookup("fresh"); !ok || entry.Hits != 1 {
+		t.Errorf("Lookup(fresh) = %+v, %v, want a hit", entry, ok)
+	}
+	if _, ok := cache.Lookup("stale"); ok {
+		t.Error("Lookup(stale) hit an expired entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale.json")); !os.IsNotExist(err) {
+		t.Error("expired entry was not removed")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	entry := func(key string) gptx.CacheEntry {
+		return gptx.CacheEntry{Key: key, Response: gptx.Response{Usage: strings.Repeat("x", 100)}}
+	}
+	used := func(key string, age time.Duration) {
+		when := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(dir, key+".json"), when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The limit fits two entries
+	probe := gptx.NewCache(t.TempDir(), 0, 0)
+	if err := probe.Store(entry("a")); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := probe.Stats()
+	cache := gptx.NewCache(dir, 0, stats.Size*2+stats.Size/2)
+
+	if err := cache.Store(entry("a")); err != nil {
+		t.Fatal(err)
+	}
+	used("a", 3*time.Minute)
+	if err := cache.Store(entry("b")); err != nil {
+		t.Fatal(err)
+	}
+	used("b", 2*time.Minute)
+	cache.Lookup("a") // a is now the most recently used
+	if err := cache.Store(entry("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.Lookup(key); ok != want {
+			t.Errorf("Lookup(%s) = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+// TestCachedReplay replays a stored response without calling the client,
+// reporting zero usage.
+func TestCachedReplay(t *testing.T) {
+	client := fake.New(fake.Turn{Events: []fake.Event{
+		{Text: "cached reply"}, {Usage: &events.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}},
+	}})
+	cached := gptx.Chain(client, gptx.Cached(gptx.NewCache(t.TempDir(), 0, 0)))
+
+	var usages []events.Usage
+	var reply strings.Builder
+	request := gptx.Request{
+		Config:   cfg.Config{Model: "gpt-4.1"},
+		Messages: []gptx.Message{{Role: "user", Content: "hi"}},
+		Events: events.HandlerFunc(func(event events.Event) {
+			switch e := event.(type) {
+			case events.Usage:
+				usages = append(usages, e)
+			case events.TextDelta:
+				reply.WriteString(e.Text)
+			}
+		}),
+	}
+
+	for range 2 {
+		if _, err := cached.SendRequest(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	response, err := cached.SendRequest(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.Requests()) != 1 {
+		t.Errorf("client received %d requests, want 1", len(client.Requests()))
+	}
+	if got, want := reply.String(), strings.Repeat("cached reply", 3); got != want {
+		t.Errorf("replies = %q, want %q", got, want)
+	}
+	if len(usages) != 3 || usages[0].TotalTokens != 15 || usages[1] != (events.Usage{}) || usages[2] != (events.Usage{}) {
+		t.Errorf("usages = %+v, want the request's then zero for replays", usages)
+	}
+	if events.ParseUsage(response.Usage) != (events.Usage{}) {
+		t.Errorf("replayed response usage = %s, want zero", response.Usage)
+	}
+}
@@ -0,0 +1,207 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+)
+
+// Capabilities describes the features and parameters a model supports.
+// Temperature ranges need no mapping: models that accept a temperature all
+// take the API's range of 0-2, and the others only accept the default.
+type Capabilities struct {
+	Temperature     bool `json:"temperature"`       // Accepts a temperature other than the default
+	Reasoning       bool `json:"reasoning"`         // Reasoning model
+	Images          bool `json:"images"`            // Accepts image inputs
+	Tools           bool `json:"tools"`             // Supports function tools
+	WebSearch       bool `json:"web_search"`        // Supports the web search tool
+	MaxOutputTokens int  `json:"max_output_tokens"` // Largest output token limit
+	ContextWindow   int  `json:"context_window"`    // Largest input and output tokens
+
+	// Costs in USD per million tokens, for routing to cheaper models
+	InputCost  float64 `json:"input_cost"`
+	OutputCost float64 `json:"output_cost"`
+}
+
+// ErrNoModelList is returned for clients that cannot list models.
+var ErrNoModelList = errors.New("client cannot list models")
+
+// DefaultTemperature is the configured temperature that leaves the model's
+// default in place.
+const DefaultTemperature float64 = 1
+
+// knownModels maps model names to their capabilities. Dated snapshots
+// (e.g. gpt-4.1-2025-04-14) match their base model.
+var knownModels = map[string]Capabilities{
+	"gpt-5": {Reasoning: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 128000, ContextWindow: 400000, InputCost: 1.25, OutputCost: 10},
+	"gpt-5-mini": {Reasoning: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 128000, ContextWindow: 400000, InputCost: 0.25, OutputCost: 2},
+	"gpt-5-nano": {Reasoning: true, Images: true, Tools: true,
+		MaxOutputTokens: 128000, ContextWindow: 400000, InputCost: 0.05, OutputCost: 0.4},
+	"gpt-4.1": {Temperature: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 32768, ContextWindow: 1047576, InputCost: 2, OutputCost: 8},
+	"gpt-4.1-mini": {Temperature: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 32768, ContextWindow: 1047576, InputCost: 0.4, OutputCost: 1.6},
+	"gpt-4.1-nano": {Temperature: true, Images: true, Tools: true,
+		MaxOutputTokens: 32768, ContextWindow: 1047576, InputCost: 0.1, OutputCost: 0.4},
+	"gpt-4o": {Temperature: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 16384, ContextWindow: 128000, InputCost: 2.5, OutputCost: 10},
+	"gpt-4o-mini": {Temperature: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 16384, ContextWindow: 128000, InputCost: 0.15, OutputCost: 0.6},
+	"o4-mini": {Reasoning: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 100000, ContextWindow: 200000, InputCost: 1.1, OutputCost: 4.4},
+	"o3": {Reasoning: true, Images: true, Tools: true, WebSearch: true,
+		MaxOutputTokens: 100000, ContextWindow: 200000, InputCost: 2, OutputCost: 8},
+	"o3-mini": {Reasoning: true, Tools: true,
+		MaxOutputTokens: 100000, ContextWindow: 200000, InputCost: 1.1, OutputCost: 4.4},
+	"o1": {Reasoning: true, Images: true, Tools: true,
+		MaxOutputTokens: 100000, ContextWindow: 200000, InputCost: 15, OutputCost: 60},
+}
+
+// imageExts are the file extensions sent to models as images.
+var imageExts = []string{".jpg", ".jpeg", ".png", ".svg", ".gif", ".webp"}
+
+// ModelCapabilities returns the capabilities of a model, matching the
+// longest known model name that the model is or is a snapshot of.
+// It reports false for unknown models.
+func ModelCapabilities(model string) (Capabilities, bool) {
+	best := ""
+	for name := range knownModels {
+		if (model == name || strings.HasPrefix(model, name+"-")) && len(name) > len(best) {
+			best = name
+		}
+	}
+	caps, ok := knownModels[best]
+	return caps, ok
+}
+
+// KnownModels returns the names of the models with capability data, sorted.
+func KnownModels() []string {
+	names := make([]string, 0, len(knownModels))
+	for name := range knownModels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ListModels lists the models available from a client, sorted by name,
+// with the capabilities of the known ones.
+func ListModels(ctx context.Context, client Client) ([]ModelInfo, error) {
+	lister, ok := client.(ModelLister)
+	if !ok {
+		return nil, ErrNoModelList
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, model := range models {
+		if caps, ok := ModelCapabilities(model.ID); ok {
+			models[i].Capabilities = &caps
+		}
+	}
+	slices.SortFunc(models, func(a, b ModelInfo) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return models, nil
+}
+
+// Check returns an error for each setting in config that the model does
+// not support.
+func (c Capabilities) Check(config cfg.Config) error {
+	var errs []error
+	if !c.Temperature && config.Temp != DefaultTemperature {
+		errs = append(errs, fmt.Errorf("temperature is not supported"))
+	}
+	if !c.Reasoning && config.Effort() != "" {
+		errs = append(errs, fmt.Errorf("reasoning is not supported"))
+	}
+	if !c.Tools && config.Shell != "" {
+		errs = append(errs, fmt.Errorf("tools are not supported (shell)"))
+	}
+	if !c.WebSearch && config.WebSearch {
+		errs = append(errs, fmt.Errorf("web search is not supported"))
+	}
+	if c.MaxOutputTokens > 0 && config.Tokens > c.MaxOutputTokens {
+		errs = append(errs, fmt.Errorf("max tokens exceeds the model limit of %d", c.MaxOutputTokens))
+	}
+	if !c.Images {
+		for _, file := range config.Files {
+			ext := strings.ToLower(filepath.Ext(file))
+			for _, image := range imageExts {
+				if ext == image {
+					errs = append(errs, fmt.Errorf("images are not supported: %s", file))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateConfig checks a configuration and its support by the selected
+// model, so that errors are reported before any request is sent.
+// Models without capability data are only checked for valid values.
+func ValidateConfig(config cfg.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if caps, ok := ModelCapabilities(config.Model); ok {
+		if err := caps.Check(config); err != nil {
+			return fmt.Errorf("model %s: %w", config.Model, err)
+		}
+	}
+	return nil
+}
+
+// MARK: Parameters
+// ============================================================================
+
+// Params are request parameters adapted to a model. Zero values leave the
+// model's defaults in place.
+type Params struct {
+	Temperature     *float64 // Sampling temperature
+	MaxOutputTokens int      // Output token limit
+	Reasoning       string   // Reasoning effort: low, medium or high
+	Summary         string   // Reasoning summary: auto, concise or detailed
+}
+
+// RequestParams adapts the configured parameters to the model: unsupported
+// parameters are dropped and the output limit is capped at the model's
+// maximum. Parameters for models without capability data are sent as
+// configured.
+func RequestParams(config cfg.Config) Params {
+	caps, known := ModelCapabilities(config.Model)
+	var params Params
+
+	// Temperature, unless the model only supports its default
+	if config.Temp != DefaultTemperature && (!known || caps.Temperature) {
+		temp := config.Temp
+		params.Temperature = &temp
+	}
+
+	// Output limit, only if set
+	if config.Tokens > 0 {
+		params.MaxOutputTokens = config.Tokens
+		if known && caps.MaxOutputTokens > 0 {
+			params.MaxOutputTokens = min(config.Tokens, caps.MaxOutputTokens)
+		}
+	}
+
+	// Reasoning, for reasoning models only
+	if config.Effort() != "" && (!known || caps.Reasoning) {
+		params.Reasoning = config.Effort()
+		if config.Summary != "none" {
+			params.Summary = config.Summary
+		}
+	}
+	return params
+}
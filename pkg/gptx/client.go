@@ -3,8 +3,10 @@ package gptx
 
 import (
 	"context"
+	"time"
 
 	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
 	"github.com/mohdfareed/gptx-cli/internal/tools"
 )
 
@@ -12,25 +14,70 @@ import (
 // This keeps the interface simple while allowing different implementations.
 type Client interface {
 	// SendRequest sends a single request with messages to the model and returns the response.
-	// The client should stream events through callbacks but doesn't need to handle the loop.
+	// The client should stream events to the request's handler but doesn't need to handle the loop.
 	SendRequest(ctx context.Context, request Request) (Response, error)
 }
 
+// ModelLister is implemented by clients that can list the models available
+// to them. It is optional, since not every provider supports it.
+type ModelLister interface {
+	// ListModels returns the models available from the provider.
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// ModelInfo describes a model available from a provider.
+type ModelInfo struct {
+	ID           string        `json:"id"`                     // Model name
+	OwnedBy      string        `json:"owned_by,omitempty"`     // Owning organization
+	Created      time.Time     `json:"created"`                // Creation time
+	Capabilities *Capabilities `json:"capabilities,omitempty"` // Known capabilities, if any
+}
+
 // Request contains all the information needed for a model request.
 // This decouples the model from the client implementation.
 type Request struct {
 	Config      cfg.Config      // Configuration
 	Messages    []Message       // Conversation history
 	ToolHandler ToolHandler     // Function to handle tool calls
-	Callbacks   ModelCallbacks  // Event callbacks
+	Events      events.Handler  // Handler of the request's events
 	ToolDefs    []tools.ToolDef // Tool definitions from registry
+	Schema      *Schema         // Optional reply schema
+}
+
+// Emit sends an event to the request's handler, if any.
+func (r Request) Emit(event events.Event) {
+	events.Emit(r.Events, event)
 }
 
-// Message represents a message in the conversation.
+// Schema constrains the model's replies to JSON matching a JSON schema.
+type Schema struct {
+	Name   string         // Schema identifier
+	Schema map[string]any // JSON schema of the reply
+}
+
+// Message represents a message in the conversation. Tool calls are
+// assistant messages with a ToolCall, followed by tool messages with the
+// call's ID and result.
 type Message struct {
-	Role    string // Role of the message sender (user, assistant, tool)
-	Content string // Content of the message
-	Name    string // Optional name for tool messages
+	Role     string       // Role of the message sender (user, assistant, tool)
+	Content  string       // Content of the message
+	Name     string       // Optional name for tool messages
+	CallID   string       // ID of the tool call a tool message answers
+	ToolCall *ToolCall    // Tool call made by the model
+	Files    []string     // Files attached to this message
+	Context  []Attachment // Context attached to this message
+	Parts    []Part       // Attachments as first sent, once converted
+}
+
+// Part is an attachment as a client converted it for sending: text, or an
+// image or document inlined as a data URL or uploaded and referenced by ID.
+// Clients send a message's parts instead of converting its files again.
+type Part struct {
+	Source string // Path of the file, or label of the context
+	Text   string // Text of a text file or context
+	Image  bool   // Whether the part is an image
+	URL    string // Data URL of an inlined image or document
+	FileID string // Provider ID of an uploaded image or document
 }
 
 // Response contains the model's response data
@@ -38,24 +85,16 @@ type Response struct {
 	Messages     []Message // Messages from the model's response
 	Usage        string    // Usage information as a JSON string
 	HasToolCalls bool      // Whether the response contains tool calls
+	Parts        []Part    // Attachments of the last user message, if converted
 }
 
 // ToolCall represents a tool call from the model
 type ToolCall struct {
+	ID        string // Call ID, linking the call to its result
 	Name      string // Name of the tool
 	Arguments string // Arguments as a JSON string
 }
 
-// ModelCallbacks defines handlers for model interaction events.
-type ModelCallbacks struct {
-	OnStart     func(cfg.Config) // Called when model starts
-	OnReply     func(string)     // Called when model returns text
-	OnReasoning func(string)     // Called when model exposes reasoning
-	OnWebSearch func()           // Called when model initiates a web search
-	OnError     func(error)      // Called when an error occurs
-	OnDone      func(string)     // Called when model completes
-}
-
 // ToolHandler is a function that handles tool calls.
-// It takes a tool name and parameters, and returns a result or an error.
-type ToolHandler func(ctx context.Context, name string, params string) (string, error)
+// It takes a tool call, and returns a result or an error.
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
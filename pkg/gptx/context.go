@@ -0,0 +1,25 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import "context"
+
+// Attachment is a labeled piece of text context attached to a message.
+type Attachment struct {
+	Label   string // What the context is (e.g. "git diff --staged")
+	Content string // The context text
+}
+
+// ContextProvider supplies attachments for a message.
+// Providers are evaluated when the message is sent, alongside its files.
+type ContextProvider interface {
+	// Context returns the attachments to send, if any.
+	Context(ctx context.Context) ([]Attachment, error)
+}
+
+// Attachments is a ContextProvider of fixed attachments.
+type Attachments []Attachment
+
+// Context returns the attachments.
+func (a Attachments) Context(context.Context) ([]Attachment, error) {
+	return a, nil
+}
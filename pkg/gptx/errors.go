@@ -0,0 +1,119 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies API errors by how they should be handled.
+type ErrorKind string
+
+// API error kinds.
+const (
+	ErrRateLimit      ErrorKind = "rate limit"      // Too many requests, retry later
+	ErrQuota          ErrorKind = "quota"           // Usage quota exhausted
+	ErrOverloaded     ErrorKind = "overloaded"      // Server error or overload
+	ErrTimeout        ErrorKind = "timeout"         // Request timed out or connection failed
+	ErrAuth           ErrorKind = "auth"            // Key missing, invalid or not permitted
+	ErrInvalidRequest ErrorKind = "invalid request" // Request rejected by the API
+	ErrUnknown        ErrorKind = "unknown"         // Unclassified error
+)
+
+// APIError is a classified error from a model API.
+type APIError struct {
+	Kind       ErrorKind     // Error classification
+	StatusCode int           // HTTP status, zero for errors without a response
+	Code       string        // Provider error code, if any
+	RetryAfter time.Duration // Delay requested by the API, zero if none
+	Err        error         // Underlying error
+}
+
+// Error returns the error message with its classification.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether requests failing with errors of this kind may
+// succeed if sent again.
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case ErrRateLimit, ErrOverloaded, ErrTimeout:
+		return true
+	}
+	return false
+}
+
+// ErrorKindOf returns the kind of an error: that of an APIError in its
+// chain, timeout for network errors, or unknown.
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return ErrTimeout
+	}
+	return ErrUnknown
+}
+
+// StatusErrorKind classifies an HTTP status and provider error code.
+func StatusErrorKind(status int, code string) ErrorKind {
+	switch {
+	case code == "insufficient_quota":
+		return ErrQuota
+	case status == http.StatusTooManyRequests || strings.Contains(code, "rate_limit"):
+		return ErrRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusRequestTimeout || strings.Contains(code, "timeout"):
+		return ErrTimeout
+	case status >= 500 || code == "server_error" || code == "overloaded":
+		return ErrOverloaded
+	case status >= 400:
+		return ErrInvalidRequest
+	}
+	return ErrUnknown
+}
+
+// RetryAfter returns the delay requested by response headers: Retry-After
+// in seconds or as a date, retry-after-ms, or the time until the request
+// or token rate limit resets. It returns zero if no delay is requested.
+func RetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(time.Until(date), 0)
+		}
+	}
+
+	// Rate limit resets are durations, such as "1s" or "6m0s"
+	var delay time.Duration
+	for _, name := range []string{"X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens"} {
+		remaining := "X-Ratelimit-Remaining-" + strings.TrimPrefix(name, "X-Ratelimit-Reset-")
+		if header.Get(remaining) != "0" {
+			continue
+		}
+		if reset, err := time.ParseDuration(header.Get(name)); err == nil {
+			delay = max(delay, reset)
+		}
+	}
+	return delay
+}
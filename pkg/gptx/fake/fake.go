@@ -0,0 +1,310 @@
+// Package fake provides a scripted gptx.Client for testing tools, agents
+// and the CLI without a network. Each request is answered by the next turn
+// of a script, and the requests received can be inspected and checked.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// Script is the sequence of turns a client replies with, one per request.
+type Script struct {
+	Turns []Turn `json:"turns"`
+}
+
+// Turn is the reply to a single request.
+type Turn struct {
+	Expect *Expect `json:"expect,omitempty"` // Checks on the request
+	Events []Event `json:"events"`           // Streamed events, in order
+}
+
+// Event is a streamed event of a turn. Exactly one of text, reasoning,
+// tool, search, error and usage is set.
+type Event struct {
+	Text      string          `json:"text,omitempty"`      // Reply text delta
+	Reasoning string          `json:"reasoning,omitempty"` // Reasoning summary
+	Tool      string          `json:"tool,omitempty"`      // Tool call name
+	Args      json.RawMessage `json:"args,omitempty"`      // Tool call arguments
+	ID        string          `json:"id,omitempty"`        // Tool call ID, generated if unset
+	Search    string          `json:"search,omitempty"`    // Web search query
+	Error     string          `json:"error,omitempty"`     // Error message, ending the turn
+	Kind      gptx.ErrorKind  `json:"kind,omitempty"`      // Error kind, for retries and fallbacks
+	Usage     *events.Usage   `json:"usage,omitempty"`     // Token usage
+}
+
+// Expect describes the request a turn expects. Empty fields are not checked.
+type Expect struct {
+	Model        string   `json:"model,omitempty"`        // Requested model
+	Messages     int      `json:"messages,omitempty"`     // Number of messages
+	Contains     []string `json:"contains,omitempty"`     // Substrings of the last message
+	Instructions []string `json:"instructions,omitempty"` // Substrings of the system prompt
+	Tools        []string `json:"tools,omitempty"`        // Defined tools
+}
+
+// Client is a gptx.Client that replies to requests from a script.
+type Client struct {
+	script   Script         // Turns to reply with
+	requests []gptx.Request // Requests received
+	mu       sync.Mutex     // Protects requests
+}
+
+// New creates a client that replies with the given turns.
+func New(turns ...Turn) *Client {
+	return &Client{script: Script{Turns: turns}}
+}
+
+// Load creates a client from a JSON script file.
+func Load(path string) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fake script: %w", err)
+	}
+
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("fake script %q: %w", path, err)
+	}
+	return &Client{script: script}, nil
+}
+
+// MARK: Script Builders
+// ============================================================================
+
+// Reply returns a turn that streams text deltas.
+func Reply(deltas ...string) Turn {
+	var turn Turn
+	for _, delta := range deltas {
+		turn.Events = append(turn.Events, Event{Text: delta})
+	}
+	return turn
+}
+
+// CallTool returns a turn that calls a tool with JSON-encoded arguments.
+func CallTool(name string, args any) Turn {
+	data, _ := json.Marshal(args)
+	return Turn{Events: []Event{{Tool: name, Args: data}}}
+}
+
+// Fail returns a turn that fails with an error of the given kind.
+func Fail(kind gptx.ErrorKind, message string) Turn {
+	return Turn{Events: []Event{{Error: message, Kind: kind}}}
+}
+
+// MARK: Client
+// ============================================================================
+
+// SendRequest replies to the request with the script's next turn,
+// streaming its events through the request's callbacks and then running
+// its tool calls with the request's tool handler.
+func (c *Client) SendRequest(ctx context.Context, request gptx.Request) (gptx.Response, error) {
+	c.mu.Lock()
+	index := len(c.requests)
+	c.requests = append(c.requests, request)
+	c.mu.Unlock()
+
+	if index >= len(c.script.Turns) {
+		return gptx.Response{}, fmt.Errorf("fake: no turn for request %d", index+1)
+	}
+	turn := c.script.Turns[index]
+	if err := turn.Expect.check(request); err != nil {
+		return gptx.Response{}, fmt.Errorf("fake: request %d: %w", index+1, err)
+	}
+
+	// Convert the new message's attachments, as a provider would
+	parts, err := convert(request.Messages)
+	if err != nil {
+		return gptx.Response{}, fmt.Errorf("fake: request %d: %w", index+1, err)
+	}
+
+	request.Emit(events.TurnStarted{Config: request.Config})
+
+	response := gptx.Response{Parts: parts}
+	var calls []gptx.ToolCall
+	var text strings.Builder
+	var usage events.Usage
+	finish := func(reason string) {
+		response.Usage = usage.String()
+		request.Emit(usage)
+		request.Emit(events.TurnFinished{Reason: reason})
+	}
+
+	for i, event := range turn.Events {
+		if err := ctx.Err(); err != nil {
+			return gptx.Response{}, err
+		}
+
+		switch {
+		case event.Text != "":
+			text.WriteString(event.Text)
+			request.Emit(events.TextDelta{Text: event.Text})
+		case event.Reasoning != "":
+			request.Emit(events.ReasoningDelta{Text: event.Reasoning})
+		case event.Tool != "":
+			call := gptx.ToolCall{ID: event.ID, Name: event.Tool, Arguments: string(event.Args)}
+			if call.ID == "" {
+				call.ID = fmt.Sprintf("call_%d_%d", index+1, i+1)
+			}
+			if call.Arguments == "" {
+				call.Arguments = "{}"
+			}
+			request.Emit(events.ToolCallDelta{ID: call.ID, Name: call.Name, Args: call.Arguments})
+			response.HasToolCalls = true
+			calls = append(calls, call)
+		case event.Search != "":
+			response.HasToolCalls = true
+			request.Emit(events.WebSearch{Query: event.Search})
+		case event.Error != "":
+			var err error = errors.New(event.Error)
+			if event.Kind != "" {
+				err = &gptx.APIError{Kind: event.Kind, Err: err}
+			}
+			request.Emit(events.Error{Err: err})
+			finish(events.FinishError)
+			return gptx.Response{}, fmt.Errorf("fake: %w", err)
+		case event.Usage != nil:
+			usage = usage.Add(*event.Usage)
+		}
+	}
+
+	if response.HasToolCalls {
+		finish(events.FinishToolCalls)
+	} else {
+		finish(events.FinishCompleted)
+	}
+
+	// Run the tool calls once the request is done
+	for _, call := range calls {
+		response.Messages = append(response.Messages,
+			gptx.Message{Role: "assistant", ToolCall: &call}, callTool(ctx, request, call))
+	}
+	if text.Len() > 0 {
+		response.Messages = append(response.Messages, gptx.Message{
+			Role: "assistant", Content: text.String(),
+		})
+	}
+	return response, nil
+}
+
+// convert reads the files and context of the last user message into text
+// parts, unless an earlier request converted them.
+func convert(messages []gptx.Message) ([]gptx.Part, error) {
+	for _, msg := range slices.Backward(messages) {
+		if msg.Role != "user" {
+			continue
+		} else if len(msg.Parts) > 0 {
+			return nil, nil
+		}
+
+		var parts []gptx.Part
+		for _, path := range msg.Files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, gptx.Part{Source: path, Text: string(data)})
+		}
+		for _, attachment := range msg.Context {
+			parts = append(parts, gptx.Part{Source: attachment.Label, Text: attachment.Content})
+		}
+		return parts, nil
+	}
+	return nil, nil
+}
+
+// callTool runs a tool call with the request's tool handler and returns
+// the message with its result or error.
+func callTool(ctx context.Context, request gptx.Request, call gptx.ToolCall) gptx.Message {
+	result := "Error: no tool handler"
+	if request.ToolHandler != nil {
+		output, err := request.ToolHandler(ctx, call)
+		if err != nil {
+			result = fmt.Sprintf("Error executing tool %s: %s", call.Name, err)
+		} else {
+			result = output
+		}
+	}
+	return gptx.Message{Role: "tool", Content: result, Name: call.Name, CallID: call.ID}
+}
+
+// MARK: Assertions
+// ============================================================================
+
+// Requests returns the requests received, in order.
+func (c *Client) Requests() []gptx.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.requests)
+}
+
+// Done returns an error unless every turn of the script was used.
+func (c *Client) Done() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) != len(c.script.Turns) {
+		return fmt.Errorf("fake: received %d of %d scripted requests",
+			len(c.requests), len(c.script.Turns))
+	}
+	return nil
+}
+
+// Expect checks that the request at index, from zero, matches expect.
+func (c *Client) Expect(index int, expect Expect) error {
+	requests := c.Requests()
+	if index < 0 || index >= len(requests) {
+		return fmt.Errorf("fake: no request %d, received %d", index+1, len(requests))
+	}
+	if err := expect.check(requests[index]); err != nil {
+		return fmt.Errorf("fake: request %d: %w", index+1, err)
+	}
+	return nil
+}
+
+// check returns an error for each expectation the request doesn't meet.
+func (e *Expect) check(request gptx.Request) error {
+	if e == nil {
+		return nil
+	}
+
+	var errs []error
+	if e.Model != "" && request.Config.Model != e.Model {
+		errs = append(errs, fmt.Errorf("model is %q, expected %q", request.Config.Model, e.Model))
+	}
+	if e.Messages > 0 && len(request.Messages) != e.Messages {
+		errs = append(errs, fmt.Errorf("%d messages, expected %d", len(request.Messages), e.Messages))
+	}
+
+	var last string
+	if len(request.Messages) > 0 {
+		last = request.Messages[len(request.Messages)-1].Content
+	}
+	for _, text := range e.Contains {
+		if !strings.Contains(last, text) {
+			errs = append(errs, fmt.Errorf("last message does not contain %q", text))
+		}
+	}
+	for _, text := range e.Instructions {
+		if !strings.Contains(request.Config.SysPrompt, text) {
+			errs = append(errs, fmt.Errorf("instructions do not contain %q", text))
+		}
+	}
+
+	for _, name := range e.Tools {
+		if !slices.ContainsFunc(request.ToolDefs, func(def tools.ToolDef) bool {
+			return def.Name == name
+		}) {
+			errs = append(errs, fmt.Errorf("tool %q is not defined", name))
+		}
+	}
+	return errors.Join(errs...)
+}
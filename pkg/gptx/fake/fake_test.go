@@ -0,0 +1,143 @@
+package fake_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/internal/tools"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx/fake"
+)
+
+// TestToolCall drives a model through a scripted tool call and checks the
+// requests the client received.
+func TestToolCall(t *testing.T) {
+	var calls []string
+	registry := tools.NewRegistry()
+	registry.Register(tools.ToolDef{
+		Name: "list", Desc: "List files",
+		Params: map[string]any{"dir": map[string]any{"type": "string"}},
+		Handler: func(_ context.Context, params map[string]any) (string, error) {
+			calls = append(calls, params["dir"].(string))
+			return "main.go\ngo.mod", nil
+		},
+	})
+
+	client := fake.New(
+		fake.CallTool("list", map[string]string{"dir": "."}),
+		fake.Reply("Two ", "files."),
+	)
+	var reply strings.Builder
+	config := cfg.Config{Model: "gpt-4.1", SysPrompt: "Be brief."}
+	model := gptx.NewModel(config, registry, gptx.WithClient(client),
+		gptx.WithCallbacks(events.Callbacks{
+			OnReply: func(text string) { reply.WriteString(text) },
+		}))
+
+	if err := model.Message(context.Background(), "List the files"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Done(); err != nil {
+		t.Error(err)
+	}
+	if got, want := reply.String(), "Two files."; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if len(calls) != 1 || calls[0] != "." {
+		t.Errorf("tool calls = %q, want [.]", calls)
+	}
+
+	// The second request carries the tool call and its result
+	requests := client.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(requests))
+	}
+	if err := client.Expect(0, fake.Expect{
+		Model: "gpt-4.1", Messages: 1, Contains: []string{"List the files"},
+		Instructions: []string{"Be brief."}, Tools: []string{"list"},
+	}); err != nil {
+		t.Error(err)
+	}
+	if err := client.Expect(1, fake.Expect{Messages: 3, Contains: []string{"main.go"}}); err != nil {
+		t.Error(err)
+	}
+	if call := requests[1].Messages[1].ToolCall; call == nil || call.Name != "list" {
+		t.Errorf("request 2 message 2 = %+v, want the list call", requests[1].Messages[1])
+	}
+}
+
+// TestDone reports unused turns and requests beyond the script.
+func TestDone(t *testing.T) {
+	client := fake.New(fake.Reply("one"), fake.Reply("two"))
+	model := gptx.NewModel(cfg.Config{Model: "gpt-4.1"}, tools.NewRegistry(),
+		gptx.WithClient(client))
+
+	if err := model.Message(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Done(); err == nil {
+		t.Error("Done() = nil with an unused turn")
+	}
+	if err := model.Message(context.Background(), "again"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Done(); err != nil {
+		t.Error(err)
+	}
+	if err := model.Message(context.Background(), "more"); err == nil {
+		t.Error("Message() = nil beyond the script")
+	}
+}
+
+// TestAttach sends an attached file once: later turns carry the parts the
+// client converted it to, without reading the file again.
+func TestAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("secret plan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.New(fake.Reply("one"), fake.Reply("two"), fake.Reply("three"))
+	model := gptx.NewModel(cfg.Config{Model: "gpt-4.1"}, tools.NewRegistry(),
+		gptx.WithClient(client))
+	ctx := context.Background()
+
+	model.Attach(path)
+	if err := model.Message(ctx, "read it"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil { // turn 2 fails if it's read again
+		t.Fatal(err)
+	}
+	if err := model.Message(ctx, "again"); err != nil {
+		t.Fatal(err)
+	}
+	model.Detach(path)
+	if err := model.Message(ctx, "forget it"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := client.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("received %d requests, want 3", len(requests))
+	}
+	if msg := requests[0].Messages[0]; !slices.Equal(msg.Files, []string{path}) || msg.Parts != nil {
+		t.Errorf("turn 1 message = %+v, want the file unconverted", msg)
+	}
+	want := []gptx.Part{{Source: path, Text: "secret plan"}}
+	if msg := requests[1].Messages[0]; !slices.Equal(msg.Parts, want) {
+		t.Errorf("turn 2 history parts = %+v, want %+v", msg.Parts, want)
+	}
+	if msg := requests[1].Messages[2]; len(msg.Files) > 0 || len(msg.Parts) > 0 {
+		t.Errorf("turn 2 message = %+v, want no attachments", msg)
+	}
+	if msg := requests[2].Messages[0]; len(msg.Files) > 0 || len(msg.Parts) > 0 {
+		t.Errorf("turn 3 history = %+v, want the file detached", msg)
+	}
+}
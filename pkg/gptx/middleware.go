@@ -0,0 +1,60 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"context"
+	"time"
+)
+
+// Middleware wraps a client to add behavior to every request, independent
+// of the provider implementing the client.
+type Middleware func(Client) Client
+
+// ClientFunc is a function that implements Client.
+type ClientFunc func(ctx context.Context, request Request) (Response, error)
+
+// SendRequest calls the function.
+func (f ClientFunc) SendRequest(ctx context.Context, request Request) (Response, error) {
+	return f(ctx, request)
+}
+
+// Chain wraps a client with middlewares. The first middleware is the
+// outermost: it sees requests first and responses last.
+func Chain(client Client, middlewares ...Middleware) Client {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = middlewares[i](client)
+	}
+	return client
+}
+
+// WithMiddleware is an option that wraps the model's client with
+// middlewares, applied in order from the outermost.
+func WithMiddleware(middlewares ...Middleware) ModelOption {
+	return func(m *Model) {
+		m.middlewares = append(m.middlewares, middlewares...)
+	}
+}
+
+// MARK: Built-in Middlewares
+// ============================================================================
+
+// Logging is a middleware that logs each request and its outcome.
+func Logging(logf func(format string, args ...any)) Middleware {
+	return func(client Client) Client {
+		return ClientFunc(func(ctx context.Context, request Request) (Response, error) {
+			logf("Request: model %s, %d messages, %d tools",
+				request.Config.Model, len(request.Messages), len(request.ToolDefs))
+
+			start := time.Now()
+			response, err := client.SendRequest(ctx, request)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logf("Request failed after %s: %v", elapsed, err)
+			} else {
+				logf("Response: %d messages, tool calls: %t, in %s",
+					len(response.Messages), response.HasToolCalls, elapsed)
+			}
+			return response, err
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package gptx_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// TestChain checks that the first middleware sees requests first and
+// responses last.
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) gptx.Middleware {
+		return func(next gptx.Client) gptx.Client {
+			return gptx.ClientFunc(func(ctx context.Context, request gptx.Request) (gptx.Response, error) {
+				calls = append(calls, name+">")
+				response, err := next.SendRequest(ctx, request)
+				calls = append(calls, "<"+name)
+				return response, err
+			})
+		}
+	}
+	client := gptx.ClientFunc(func(context.Context, gptx.Request) (gptx.Response, error) {
+		calls = append(calls, "client")
+		return gptx.Response{}, nil
+	})
+
+	chained := gptx.Chain(client, trace("a"), trace("b"), trace("c"))
+	if _, err := chained.SendRequest(context.Background(), gptx.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a>", "b>", "c>", "client", "<c", "<b", "<a"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+
+	// Without middlewares the client is called directly
+	calls = nil
+	if _, err := gptx.Chain(client).SendRequest(context.Background(), gptx.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(calls, []string{"client"}) {
+		t.Errorf("calls = %q, want only the client", calls)
+	}
+}
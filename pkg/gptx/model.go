@@ -5,6 +5,8 @@ package gptx
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/mohdfareed/gptx-cli/internal/cfg"
 	"github.com/mohdfareed/gptx-cli/internal/events"
@@ -18,12 +20,22 @@ import (
 // 3. Handles event callbacks
 // 4. Delegates to the API client
 type Model struct {
-	client       Client           // API client
-	config       cfg.Config       // Configuration
-	toolRegistry *tools.Registry  // Tool registry
-	callbacks    events.Callbacks // Event callbacks
+	client       Client            // API client
+	middlewares  []Middleware      // Client middlewares, outermost first
+	config       cfg.Config        // Configuration
+	toolRegistry *tools.Registry   // Tool registry
+	events       *events.Bus       // Subscribers to the model's events
+	history      []Message         // Conversation history
+	files        []string          // Files to attach to the next message
+	providers    []ContextProvider // Context to attach to the next message
+	schema       *Schema           // Reply schema
+	toolHooks    []ToolHook        // Checks of tool calls before they run
 }
 
+// ToolHook checks a tool call before it runs. It returns the call to run,
+// which may have rewritten arguments, or an error to reject the call.
+type ToolHook func(ctx context.Context, call ToolCall) (ToolCall, error)
+
 // ModelOption is a function that configures a Model.
 // This follows the functional options pattern for clean configuration.
 type ModelOption func(*Model)
@@ -36,6 +48,8 @@ func NewModel(
 	model := &Model{
 		config:       config,
 		toolRegistry: tools,
+		files:        config.Files, // attached to the first message
+		events:       &events.Bus{},
 	}
 
 	// Apply all options
@@ -43,6 +57,11 @@ func NewModel(
 		option(model)
 	}
 
+	// Wrap the client once it and its middlewares are set
+	if model.client != nil {
+		model.client = Chain(model.client, model.middlewares...)
+	}
+
 	return model
 }
 
@@ -53,10 +72,40 @@ func WithClient(client Client) ModelOption {
 	}
 }
 
-// WithCallbacks is an option that sets the event callbacks.
+// WithCallbacks is an option that subscribes event callbacks to the model.
 func WithCallbacks(callbacks events.Callbacks) ModelOption {
+	return WithHandler(callbacks)
+}
+
+// WithHandler is an option that subscribes event handlers to the model.
+func WithHandler(handlers ...events.Handler) ModelOption {
+	return func(m *Model) {
+		for _, handler := range handlers {
+			m.events.Subscribe(handler)
+		}
+	}
+}
+
+// WithToolHook is an option that checks tool calls with hooks, in order,
+// before they run. Rejected calls return the hook's error to the model.
+func WithToolHook(hooks ...ToolHook) ModelOption {
+	return func(m *Model) {
+		m.toolHooks = append(m.toolHooks, hooks...)
+	}
+}
+
+// WithContext is an option that attaches context from providers
+// to the first message.
+func WithContext(providers ...ContextProvider) ModelOption {
 	return func(m *Model) {
-		m.callbacks = callbacks
+		m.providers = append(m.providers, providers...)
+	}
+}
+
+// WithSchema is an option that constrains replies to JSON matching schema.
+func WithSchema(schema Schema) ModelOption {
+	return func(m *Model) {
+		m.schema = &schema
 	}
 }
 
@@ -66,6 +115,12 @@ func (m *Model) RegisterTool(tool tools.ToolDef) {
 	m.toolRegistry.Register(tool)
 }
 
+// Subscribe adds a handler of the model's events and returns a function
+// that removes it.
+func (m *Model) Subscribe(handler events.Handler) func() {
+	return m.events.Subscribe(handler)
+}
+
 // Config returns the model's configuration.
 func (m *Model) Config() cfg.Config {
 	return m.config
@@ -76,55 +131,104 @@ func (m *Model) Tools() []tools.ToolDef {
 	return m.toolRegistry.GetDefinitions()
 }
 
-// Message sends a message to the model and processes the response through callbacks.
+// History returns the conversation so far.
+func (m *Model) History() []Message {
+	return append([]Message(nil), m.history...)
+}
+
+// Attach adds files to the next message sent to the model. Files are read
+// and converted once; later requests send the parts the client returned.
+func (m *Model) Attach(files ...string) {
+	m.files = append(m.files, files...)
+}
+
+// AttachContext adds context providers to the next message sent to the model.
+func (m *Model) AttachContext(providers ...ContextProvider) {
+	m.providers = append(m.providers, providers...)
+}
+
+// Detach drops files, or context with matching labels, from the next message
+// and from earlier messages, so they are no longer sent in the conversation.
+func (m *Model) Detach(files ...string) {
+	drop := func(attached []string) []string {
+		var kept []string
+		for _, file := range attached {
+			if !slices.Contains(files, file) {
+				kept = append(kept, file)
+			}
+		}
+		return kept
+	}
+
+	// Copy the history, since requests already sent share it
+	m.files = drop(m.files)
+	m.history = slices.Clone(m.history)
+	for i := range m.history {
+		m.history[i].Files = drop(m.history[i].Files)
+		m.history[i].Context = slices.DeleteFunc(
+			slices.Clone(m.history[i].Context), func(a Attachment) bool {
+				return slices.Contains(files, a.Label)
+			})
+		m.history[i].Parts = slices.DeleteFunc(
+			slices.Clone(m.history[i].Parts), func(p Part) bool {
+				return slices.Contains(files, p.Source)
+			})
+	}
+}
+
+// Message sends a message to the model and reports the response's events to subscribers.
 // It manages the conversation loop for handling tool calls and errors.
-func (m *Model) Message(ctx context.Context, prompt string) error {
+func (m *Model) Message(ctx context.Context, prompt string) (err error) {
 	if m.client == nil {
 		return fmt.Errorf("no client set, use WithClient option")
 	}
+	m.events.HandleEvent(events.RunStarted{Config: m.config})
+	defer func() { m.events.HandleEvent(events.RunFinished{Err: err}) }()
 
-	// Create the tool handler function
-	toolHandler := func(ctx context.Context, name string, params string) (string, error) {
-		// Notify about the tool call
-		if m.callbacks.OnToolCall != nil {
-			toolCall := tools.ToolCall{Name: name, Params: params}
-			m.callbacks.OnToolCall(toolCall)
+	// Run tool calls through the registry, reporting them as events
+	toolHandler := func(ctx context.Context, call ToolCall) (string, error) {
+		var err error
+		for _, hook := range m.toolHooks {
+			var checked ToolCall
+			if checked, err = hook(ctx, call); err != nil {
+				break
+			}
+			call = checked
 		}
-
-		// Execute the tool
-		result, err := m.toolRegistry.Execute(ctx, tools.ToolCall{
-			Name: name, Params: params,
+		m.events.HandleEvent(events.ToolCallStarted{
+			ID: call.ID, Name: call.Name, Args: call.Arguments,
 		})
 
-		// Handle errors
-		if err != nil {
-			return "", err
-		}
-
-		// Report tool results through callback
-		if m.callbacks.OnToolResult != nil {
-			m.callbacks.OnToolResult(result)
+		start := time.Now()
+		var result string
+		if err == nil {
+			result, err = m.toolRegistry.Execute(ctx, tools.ToolCall{
+				Name: call.Name, Params: call.Arguments,
+			})
 		}
-
-		return result, nil
+		m.events.HandleEvent(events.ToolCallFinished{
+			ID: call.ID, Name: call.Name, Result: result, Err: err,
+			Duration: time.Since(start),
+		})
+		return result, err
 	}
 
-	// Create callbacks for the client
-	clientCallbacks := ModelCallbacks{
-		OnStart:     m.callbacks.OnStart,
-		OnReply:     m.callbacks.OnReply,
-		OnReasoning: m.callbacks.OnReasoning,
-		OnError:     m.callbacks.OnError,
-		OnDone:      m.callbacks.OnDone,
-		OnWebSearch: func() {
-			m.callbacks.OnToolCall(tools.ToolCall{Name: "web_search"})
-		},
+	// Collect context for the message from the attached providers
+	var attachments []Attachment
+	for _, provider := range m.providers {
+		attached, err := provider.Context(ctx)
+		if err != nil {
+			return fmt.Errorf("context: %w", err)
+		}
+		attachments = append(attachments, attached...)
 	}
 
-	// Initialize the conversation with the user message
-	messages := []Message{
-		{Role: "user", Content: prompt},
-	}
+	// Continue the conversation with the user message and its attachments
+	messages := append(m.History(), Message{
+		Role: "user", Content: prompt,
+		Files: m.files, Context: attachments,
+	})
+	prompted := len(messages) - 1
 
 	// Initialize loop control variables
 	maxIterations := 10
@@ -137,8 +241,9 @@ func (m *Model) Message(ctx context.Context, prompt string) error {
 			Config:      m.config,
 			Messages:    messages,
 			ToolHandler: toolHandler,
-			Callbacks:   clientCallbacks,
+			Events:      m.events,
 			ToolDefs:    m.Tools(),
+			Schema:      m.schema,
 		}
 
 		// Send the request to the client and get the response
@@ -147,6 +252,12 @@ func (m *Model) Message(ctx context.Context, prompt string) error {
 			return fmt.Errorf("send request: %w", err)
 		}
 
+		// Send the attachments as converted from now on
+		if len(response.Parts) > 0 {
+			messages = slices.Clone(messages) // the request keeps its own
+			messages[prompted].Parts = response.Parts
+		}
+
 		// Add new messages from the response to our conversation
 		messages = append(messages, response.Messages...)
 
@@ -154,5 +265,8 @@ func (m *Model) Message(ctx context.Context, prompt string) error {
 		hasToolCalls = response.HasToolCalls
 	}
 
+	// Record the turn; its files stay with its message, not the next one
+	m.history = messages
+	m.files, m.providers = nil, nil
 	return nil
 }
@@ -0,0 +1,137 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	Attempts  int           // Retries after the first attempt
+	BaseDelay time.Duration // Delay before the first retry
+	MaxDelay  time.Duration // Longest delay between attempts
+}
+
+// DefaultRetryPolicy retries three times, starting after about a second.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute,
+}
+
+// Delay returns the delay before a retry: the delay requested by the API
+// if any, or an exponential backoff with jitter.
+func (p RetryPolicy) Delay(retry int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, p.MaxDelay)
+	}
+
+	delay := p.BaseDelay
+	for i := 0; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	// Spread retries over the upper half of the delay
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryClient retries requests that fail with retryable errors.
+type retryClient struct {
+	client Client      // Client that sends the requests
+	policy RetryPolicy // Retry policy
+}
+
+// Retry is a middleware that retries requests failing with rate limit,
+// overload or timeout errors. Requests that fail after streaming output
+// are not retried, since a retry regenerates the whole reply. Errors are
+// only reported once no retries are left.
+func Retry(policy RetryPolicy) Middleware {
+	return func(client Client) Client {
+		return &retryClient{client: client, policy: policy}
+	}
+}
+
+// SendRequest sends the request, retrying it according to the policy.
+func (c *retryClient) SendRequest(ctx context.Context, request Request) (Response, error) {
+	var started, streamed bool
+	for retry := 0; ; retry++ {
+		// Turns start once, whichever attempt serves them
+		attempt := request
+		var report func()
+		attempt.Events, report = deferReports(trackStreamed(&streamed,
+			events.HandlerFunc(func(event events.Event) {
+				if _, ok := event.(events.TurnStarted); ok {
+					if started {
+						return
+					}
+					started = true
+				}
+				request.Emit(event)
+			})))
+
+		// Errors and completion are reported once the attempt is final
+		response, err := c.client.SendRequest(ctx, attempt)
+		if err == nil || streamed || retry >= c.policy.Attempts || ctx.Err() != nil ||
+			!ErrorKindOf(err).Retryable() {
+			report()
+			return response, err
+		}
+
+		delay := c.policy.Delay(retry, err)
+		request.Emit(events.Retry{Err: err, Delay: delay})
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// deferReports returns a handler that holds back errors, usage and
+// completion until report is called, so that attempts that are retried or
+// failed over are not reported. Turns that finish successfully are reported
+// at once, since they are not retried, before their tool calls run.
+func deferReports(handler events.Handler) (events.Handler, func()) {
+	var deferred []events.Event
+	report := func() {
+		for _, event := range deferred {
+			events.Emit(handler, event)
+		}
+		deferred = nil
+	}
+	held := events.HandlerFunc(func(event events.Event) {
+		switch e := event.(type) {
+		case events.TurnFinished:
+			deferred = append(deferred, event)
+			if e.Reason == events.FinishCompleted || e.Reason == events.FinishToolCalls {
+				report()
+			}
+		case events.Error, events.Usage:
+			deferred = append(deferred, event)
+		default:
+			events.Emit(handler, event)
+		}
+	})
+	return held, report
+}
+
+// trackStreamed returns a handler that records whether the model streamed
+// any output: reply or reasoning text, or tool calls. Output can't be taken
+// back, so requests that streamed it are neither retried nor failed over.
+func trackStreamed(streamed *bool, handler events.Handler) events.Handler {
+	return events.HandlerFunc(func(event events.Event) {
+		switch event.(type) {
+		case events.TextDelta, events.ReasoningDelta, events.ToolCallDelta,
+			events.ToolCallStarted:
+			*streamed = true
+		}
+		events.Emit(handler, event)
+	})
+}
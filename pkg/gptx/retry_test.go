@@ -0,0 +1,154 @@
+package gptx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		name     string
+		retry    int
+		err      error
+		min, max time.Duration
+	}{
+		{"first retry", 0, errors.New("x"), 500 * time.Millisecond, time.Second},
+		{"backoff", 2, errors.New("x"), 2 * time.Second, 4 * time.Second},
+		{"capped", 10, errors.New("x"), 2500 * time.Millisecond, 5 * time.Second},
+		{"retry after", 0, &APIError{Kind: ErrRateLimit, RetryAfter: 3 * time.Second},
+			3 * time.Second, 3 * time.Second},
+		{"retry after capped", 0, &APIError{Kind: ErrRateLimit, RetryAfter: time.Hour},
+			5 * time.Second, 5 * time.Second},
+	}
+	for _, test := range tests {
+		for range 20 { // jitter
+			if delay := policy.Delay(test.retry, test.err); delay < test.min || delay > test.max {
+				t.Errorf("%s: Delay() = %s, want %s-%s", test.name, delay, test.min, test.max)
+				break
+			}
+		}
+	}
+	if delay := (RetryPolicy{}).Delay(0, errors.New("x")); delay != 0 {
+		t.Errorf("zero policy Delay() = %s, want 0", delay)
+	}
+}
+
+func TestDeferReports(t *testing.T) {
+	usage := events.Usage{TotalTokens: 3}
+	failed := events.Error{Err: errors.New("x")}
+	tests := []struct {
+		name   string
+		events []events.Event
+		early  []events.Event // Reported before report is called
+	}{
+		{"completed",
+			[]events.Event{events.TextDelta{Text: "a"}, usage, events.TurnFinished{Reason: events.FinishCompleted}},
+			[]events.Event{events.TextDelta{Text: "a"}, usage, events.TurnFinished{Reason: events.FinishCompleted}}},
+		{"tool calls",
+			[]events.Event{usage, events.TurnFinished{Reason: events.FinishToolCalls}},
+			[]events.Event{usage, events.TurnFinished{Reason: events.FinishToolCalls}}},
+		{"failed",
+			[]events.Event{events.TurnStarted{}, failed, usage, events.TurnFinished{Reason: events.FinishError}},
+			[]events.Event{events.TurnStarted{}}},
+	}
+	for _, test := range tests {
+		var got []events.Event
+		handler, report := deferReports(events.HandlerFunc(func(event events.Event) {
+			got = append(got, event)
+		}))
+		for _, event := range test.events {
+			handler.HandleEvent(event)
+		}
+		if !reflect.DeepEqual(got, test.early) {
+			t.Errorf("%s: before report = %v, want %v", test.name, got, test.early)
+		}
+		report()
+		report() // reports once
+		if !reflect.DeepEqual(got, test.events) {
+			t.Errorf("%s: after report = %v, want %v", test.name, got, test.events)
+		}
+	}
+}
+
+func TestTrackStreamed(t *testing.T) {
+	tests := []struct {
+		event    events.Event
+		streamed bool
+	}{
+		{events.TurnStarted{}, false},
+		{events.Usage{}, false},
+		{events.Error{Err: errors.New("x")}, false},
+		{events.TextDelta{Text: "a"}, true},
+		{events.ReasoningDelta{Text: "a"}, true},
+		{events.ToolCallDelta{Name: "ls"}, true},
+		{events.ToolCallStarted{Name: "ls"}, true},
+	}
+	for _, test := range tests {
+		var streamed bool
+		var passed []events.Event
+		handler := trackStreamed(&streamed, events.HandlerFunc(func(event events.Event) {
+			passed = append(passed, event)
+		}))
+		handler.HandleEvent(test.event)
+		if streamed != test.streamed || len(passed) != 1 {
+			t.Errorf("%T: streamed = %v, want %v", test.event, streamed, test.streamed)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 2}
+	rateLimited := &APIError{Kind: ErrRateLimit, Err: errors.New("slow down")}
+	tests := []struct {
+		name     string
+		attempts []error // Result of each attempt
+		stream   bool    // Whether attempts stream text before failing
+		calls    int
+		retries  int
+		err      bool
+	}{
+		{"succeeds", []error{nil}, false, 1, 0, false},
+		{"retries", []error{rateLimited, rateLimited, nil}, false, 3, 2, false},
+		{"gives up", []error{rateLimited, rateLimited, rateLimited}, false, 3, 2, true},
+		{"not retryable", []error{&APIError{Kind: ErrAuth, Err: errors.New("no")}}, false, 1, 0, true},
+		{"streamed", []error{rateLimited, nil}, true, 1, 0, true},
+	}
+
+	for _, test := range tests {
+		calls := 0
+		client := ClientFunc(func(ctx context.Context, request Request) (Response, error) {
+			err := test.attempts[calls]
+			calls++
+			request.Emit(events.TurnStarted{})
+			if test.stream {
+				request.Emit(events.TextDelta{Text: "partial"})
+			}
+			if err != nil {
+				request.Emit(events.Error{Err: err})
+				request.Emit(events.TurnFinished{Reason: events.FinishError})
+				return Response{}, err
+			}
+			request.Emit(events.TurnFinished{Reason: events.FinishCompleted})
+			return Response{}, nil
+		})
+
+		counts := map[string]int{}
+		request := Request{Events: events.HandlerFunc(func(event events.Event) {
+			counts[reflect.TypeOf(event).Name()]++
+		})}
+		_, err := Chain(client, Retry(policy)).SendRequest(context.Background(), request)
+		if (err != nil) != test.err || calls != test.calls || counts["Retry"] != test.retries {
+			t.Errorf("%s: error = %v, calls = %d, retries = %d, want error %v, %d calls, %d retries",
+				test.name, err, calls, counts["Retry"], test.err, test.calls, test.retries)
+		}
+		if counts["TurnStarted"] != 1 || counts["TurnFinished"] != 1 || counts["Error"] != map[bool]int{true: 1}[test.err] {
+			t.Errorf("%s: events = %v, want one turn and only the final error", test.name, counts)
+		}
+	}
+}
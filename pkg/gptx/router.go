@@ -0,0 +1,220 @@
+// Package gptx provides core model interaction logic.
+package gptx
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/internal/events"
+)
+
+// Backend is a model served by a provider's client.
+type Backend struct {
+	Provider string // Model provider
+	Model    string // Model name
+	Client   Client // Client of the provider
+}
+
+// String returns the backend as provider/model.
+func (b Backend) String() string {
+	return b.Provider + "/" + b.Model
+}
+
+// Requirements are the features a request needs from a model.
+type Requirements struct {
+	Images    bool // Attaches images
+	Tools     bool // Defines function tools
+	WebSearch bool // Uses web search
+	Tokens    int  // Estimated input tokens
+	Output    int  // Output limit, if set
+}
+
+// RequestRequirements estimates the features a request needs. Input tokens
+// are estimated at four characters each.
+func RequestRequirements(request Request) Requirements {
+	config := request.Config
+	reqs := Requirements{
+		Tools:     len(request.ToolDefs) > 0,
+		WebSearch: config.WebSearch,
+	}
+
+	chars := len(config.SysPrompt)
+	for _, msg := range request.Messages {
+		chars += len(msg.Content)
+		for _, attachment := range msg.Context {
+			chars += len(attachment.Content)
+		}
+		for _, file := range msg.Files {
+			if slices.Contains(imageExts, strings.ToLower(filepath.Ext(file))) {
+				reqs.Images = true
+			} else if info, err := os.Stat(file); err == nil {
+				chars += int(info.Size())
+			}
+		}
+	}
+	reqs.Tokens, reqs.Output = chars/4, config.Tokens
+	return reqs
+}
+
+// Supports reports whether a model with these capabilities can serve a
+// request with the given requirements.
+func (c Capabilities) Supports(reqs Requirements) bool {
+	switch {
+	case reqs.Images && !c.Images, reqs.Tools && !c.Tools,
+		reqs.WebSearch && !c.WebSearch:
+		return false
+	case c.ContextWindow > 0 && reqs.Tokens+reqs.Output > c.ContextWindow:
+		return false
+	}
+	return true
+}
+
+// MARK: Router
+// ============================================================================
+
+// estimatedOutput is the output tokens assumed for routing requests that
+// don't limit their output.
+const estimatedOutput = 1000
+
+// router sends requests to the first backend that serves them.
+type router struct {
+	backends []Backend // Backends in order of preference
+	cheapest bool      // Prefer the cheapest capable backend
+}
+
+// NewRouter creates a client that sends requests to backends in order,
+// failing over to the next one on rate limit, quota, overload, timeout and
+// auth errors. Backends known not to support a request are skipped. With
+// cheapest, capable backends are tried from the cheapest, with backends
+// of unknown cost last. Each failover is reported as a Failover event, and
+// the backend serving the request as a Route event.
+func NewRouter(backends []Backend, cheapest bool) Client {
+	return &router{backends: backends, cheapest: cheapest}
+}
+
+// SendRequest sends the request to the backends until one serves it.
+func (r *router) SendRequest(ctx context.Context, request Request) (Response, error) {
+	if len(r.backends) == 0 {
+		return Response{}, fmt.Errorf("router: no backends")
+	}
+	candidates, reason := r.candidates(request)
+
+	var err error
+	for i, backend := range candidates {
+		if i > 0 {
+			request.Emit(events.Failover{
+				From: candidates[i-1].String(), To: backend.String(), Err: err,
+			})
+			reason = fmt.Sprintf("fallback after %s error from %s",
+				ErrorKindOf(err), candidates[i-1])
+		}
+
+		// Errors are only reported if there is no backend to fail over to,
+		// and requests that streamed output are not failed over
+		attempt := request
+		attempt.Config.Provider, attempt.Config.Model = backend.Provider, backend.Model
+		var report func()
+		var streamed bool
+		routed, route := routeOnce(events.Route{Backend: backend.String(), Reason: reason}, request.Events)
+		attempt.Events, report = deferReports(trackStreamed(&streamed, routed))
+
+		var response Response
+		response, err = backend.Client.SendRequest(ctx, attempt)
+		if err == nil {
+			route()
+		}
+		if err == nil || streamed || ctx.Err() != nil || !failover(ErrorKindOf(err)) ||
+			i == len(candidates)-1 {
+			report()
+			return response, err
+		}
+	}
+	return Response{}, err
+}
+
+// routeOnce returns a handler that emits route before the first output or
+// successful completion of an attempt, once the backend serves the request,
+// and a function that emits it if it wasn't yet.
+func routeOnce(route events.Route, handler events.Handler) (events.Handler, func()) {
+	var routed bool
+	emit := func() {
+		if !routed {
+			routed = true
+			events.Emit(handler, route)
+		}
+	}
+	return events.HandlerFunc(func(event events.Event) {
+		switch e := event.(type) {
+		case events.TextDelta, events.ReasoningDelta, events.ToolCallDelta,
+			events.ToolCallStarted, events.WebSearch:
+			emit()
+		case events.TurnFinished:
+			if e.Reason == events.FinishCompleted || e.Reason == events.FinishToolCalls {
+				emit()
+			}
+		}
+		events.Emit(handler, event)
+	}), emit
+}
+
+// candidates returns the backends to try for a request, in order, and the
+// reason the first one is selected.
+func (r *router) candidates(request Request) ([]Backend, string) {
+	reqs := RequestRequirements(request)
+	var capable []Backend
+	for _, backend := range r.backends {
+		if caps, ok := ModelCapabilities(backend.Model); !ok || caps.Supports(reqs) {
+			capable = append(capable, backend)
+		}
+	}
+	if len(capable) == 0 {
+		return r.backends, "no capable backend"
+	}
+	if !r.cheapest {
+		if capable[0].String() == r.backends[0].String() {
+			return capable, "primary"
+		}
+		return capable, "first capable backend"
+	}
+
+	// Cost of the request on each backend, unknown costs last
+	output := float64(reqs.Output)
+	if output == 0 {
+		output = estimatedOutput
+	}
+	cost := func(backend Backend) float64 {
+		caps, ok := ModelCapabilities(backend.Model)
+		if !ok || caps.InputCost == 0 {
+			return -1
+		}
+		return caps.InputCost*float64(reqs.Tokens) + caps.OutputCost*output
+	}
+	slices.SortStableFunc(capable, func(a, b Backend) int {
+		costA, costB := cost(a), cost(b)
+		switch {
+		case costA < 0 && costB < 0:
+			return 0
+		case costA < 0:
+			return 1
+		case costB < 0:
+			return -1
+		}
+		return cmp.Compare(costA, costB)
+	})
+	return capable, "cheapest capable backend"
+}
+
+// failover reports whether errors of a kind may not occur on another
+// backend.
+func failover(kind ErrorKind) bool {
+	switch kind {
+	case ErrRateLimit, ErrQuota, ErrOverloaded, ErrTimeout, ErrAuth:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package gptx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mohdfareed/gptx-cli/internal/cfg"
+	"github.com/mohdfareed/gptx-cli/internal/events"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/mohdfareed/gptx-cli/pkg/gptx/fake"
+)
+
+// recorder collects the events of a request.
+type recorder []events.Event
+
+func (r *recorder) HandleEvent(event events.Event) {
+	*r = append(*r, event)
+}
+
+// routes returns the recorded routes and failovers.
+func (r recorder) routes() (routes []events.Route, failovers []events.Failover) {
+	for _, event := range r {
+		switch e := event.(type) {
+		case events.Route:
+			routes = append(routes, e)
+		case events.Failover:
+			failovers = append(failovers, e)
+		}
+	}
+	return routes, failovers
+}
+
+func TestRouterCandidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		models    []string // Backends, in order of preference
+		cheapest  bool
+		webSearch bool
+		want      events.Route
+	}{
+		{"primary", []string{"gpt-4.1", "gpt-4.1-nano"}, false, false,
+			events.Route{Backend: "openai/gpt-4.1", Reason: "primary"}},
+		{"first capable", []string{"gpt-4.1-nano", "gpt-4.1"}, false, true,
+			events.Route{Backend: "openai/gpt-4.1", Reason: "first capable backend"}},
+		{"cheapest", []string{"gpt-4.1", "custom", "gpt-4.1-nano", "gpt-4.1-mini"}, true, false,
+			events.Route{Backend: "openai/gpt-4.1-nano", Reason: "cheapest capable backend"}},
+		{"cheapest capable", []string{"gpt-4.1", "gpt-4.1-nano", "gpt-4.1-mini"}, true, true,
+			events.Route{Backend: "openai/gpt-4.1-mini", Reason: "cheapest capable backend"}},
+		{"unknown cost last", []string{"custom", "gpt-4.1"}, true, false,
+			events.Route{Backend: "openai/gpt-4.1", Reason: "cheapest capable backend"}},
+		{"none capable", []string{"gpt-4.1-nano", "o3-mini"}, true, true,
+			events.Route{Backend: "openai/gpt-4.1-nano", Reason: "no capable backend"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var backends []gptx.Backend
+			for _, model := range test.models {
+				backends = append(backends, gptx.Backend{
+					Provider: "openai", Model: model, Client: fake.New(fake.Reply("ok")),
+				})
+			}
+
+			var recorded recorder
+			request := gptx.Request{
+				Config:   cfg.Config{WebSearch: test.webSearch},
+				Messages: []gptx.Message{{Role: "user", Content: "hi"}},
+				Events:   &recorded,
+			}
+			router := gptx.NewRouter(backends, test.cheapest)
+			if _, err := router.SendRequest(context.Background(), request); err != nil {
+				t.Fatal(err)
+			}
+
+			routes, _ := recorded.routes()
+			if len(routes) != 1 || routes[0] != test.want {
+				t.Errorf("routes = %+v, want %+v", routes, test.want)
+			}
+			for _, backend := range backends {
+				served := backend.String() == test.want.Backend
+				requests := backend.Client.(*fake.Client).Requests()
+				if got := len(requests) > 0; got != served {
+					t.Errorf("%s received %d requests", backend, len(requests))
+				} else if served && requests[0].Config.Model != backend.Model {
+					t.Errorf("%s was sent model %s", backend, requests[0].Config.Model)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterFailover(t *testing.T) {
+	rateLimited := fake.Fail(gptx.ErrRateLimit, "slow down")
+	partial := fake.Turn{Events: []fake.Event{
+		{Text: "partial"}, {Error: "slow down", Kind: gptx.ErrRateLimit},
+	}}
+	tests := []struct {
+		name      string
+		turns     []fake.Turn // Replies of each backend, in order
+		err       bool
+		failovers int
+		route     string // Serving backend, if any
+	}{
+		{"fails over", []fake.Turn{rateLimited, fake.Reply("ok")}, false, 1, "openai/b"},
+		{"fails over twice", []fake.Turn{rateLimited, rateLimited, fake.Reply("ok")}, false, 2, "openai/c"},
+		{"invalid request", []fake.Turn{fake.Fail(gptx.ErrInvalidRequest, "bad"), fake.Reply("ok")}, true, 0, ""},
+		{"streamed output", []fake.Turn{partial, fake.Reply("ok")}, true, 0, "openai/a"},
+		{"last backend", []fake.Turn{rateLimited, rateLimited}, true, 1, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var backends []gptx.Backend
+			for i, turn := range test.turns {
+				backends = append(backends, gptx.Backend{
+					Provider: "openai", Model: string(rune('a' + i)), Client: fake.New(turn),
+				})
+			}
+
+			var recorded recorder
+			request := gptx.Request{
+				Messages: []gptx.Message{{Role: "user", Content: "hi"}},
+				Events:   &recorded,
+			}
+			_, err := gptx.NewRouter(backends, false).SendRequest(context.Background(), request)
+			if (err != nil) != test.err {
+				t.Fatalf("SendRequest() error = %v, want error %v", err, test.err)
+			}
+
+			routes, failovers := recorded.routes()
+			if len(failovers) != test.failovers {
+				t.Errorf("failovers = %+v, want %d", failovers, test.failovers)
+			}
+			for i, failover := range failovers {
+				if failover.From != backends[i].String() || failover.To != backends[i+1].String() ||
+					gptx.ErrorKindOf(failover.Err) != gptx.ErrRateLimit {
+					t.Errorf("failover %d = %+v", i, failover)
+				}
+			}
+			if test.route == "" && len(routes) > 0 {
+				t.Errorf("routes = %+v, want none", routes)
+			} else if test.route != "" && (len(routes) != 1 || routes[0].Backend != test.route) {
+				t.Errorf("routes = %+v, want one to %s", routes, test.route)
+			}
+			if len(routes) == 1 && test.failovers > 0 {
+				want := "fallback after rate limit error from " + failovers[len(failovers)-1].From
+				if routes[0].Reason != want {
+					t.Errorf("route reason = %q, want %q", routes[0].Reason, want)
+				}
+			}
+
+			// Only the final attempt's error is reported
+			errors := 0
+			for _, event := range recorded {
+				if _, ok := event.(events.Error); ok {
+					errors++
+				}
+			}
+			if want := map[bool]int{true: 1}[test.err]; errors != want {
+				t.Errorf("reported %d errors, want %d", errors, want)
+			}
+		})
+	}
+}
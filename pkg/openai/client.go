@@ -3,8 +3,12 @@ package openai
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 
+	"github.com/mohdfareed/gptx-cli/internal/events"
 	"github.com/mohdfareed/gptx-cli/pkg/gptx"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -13,14 +17,22 @@ import (
 
 // OpenAIClient implements the gptx.Client interface for the OpenAI API.
 type OpenAIClient struct {
-	client openai.Client // OpenAI SDK client
-	userID string        // User identifier for API tracking
+	client    openai.Client // OpenAI SDK client
+	userID    string        // User identifier for API tracking
+	uploads   *UploadCache  // Files API upload cache
+	threshold int64         // Upload files larger than this size (bytes)
+	account   string        // Base URL and key fingerprint, scoping uploads
+	keyHash   string        // Fingerprint of the API key
 }
 
 // NewOpenAIClient creates a new OpenAI client with the provided API key.
-func NewOpenAIClient(apiKey string) *OpenAIClient {
+// Additional SDK options, such as a custom base URL, are applied in order.
+func NewOpenAIClient(apiKey string, opts ...option.RequestOption) *OpenAIClient {
+	opts = append([]option.RequestOption{option.WithAPIKey(apiKey)}, opts...)
+	sum := sha256.Sum256([]byte(apiKey))
 	return &OpenAIClient{
-		client: openai.NewClient(option.WithAPIKey(apiKey)),
+		client:  openai.NewClient(opts...),
+		keyHash: hex.EncodeToString(sum[:8]),
 	}
 }
 
@@ -37,15 +49,31 @@ func (c *OpenAIClient) SendRequest(ctx context.Context, request gptx.Request) (g
 	var openAIMessages []MsgData
 
 	// Process all messages in the conversation
+	var converted []gptx.Part
 	for _, msg := range request.Messages {
-		// Handle file attachments for user messages
-		if msg.Role == "user" && len(request.Config.Files) > 0 {
-			// Only attach files to the latest user message
-			userMsg, err := UserMsg(msg.Content, request.Config.Files)
+		if msg.Role == "user" {
+			converted = nil // only the last user message's parts are returned
+		}
+
+		// Handle attachments for user messages
+		if msg.Role == "user" && len(msg.Parts) > 0 {
+			// Attachments converted by an earlier request are sent as they were
+			openAIMessages = append(openAIMessages, userMsg(msg.Content, msg.Parts))
+		} else if msg.Role == "user" && (len(msg.Files) > 0 || len(msg.Context) > 0) {
+			parts, err := c.parts(ctx, msg)
 			if err != nil {
 				return gptx.Response{}, fmt.Errorf("openai: %w", err)
 			}
-			openAIMessages = append(openAIMessages, userMsg)
+			openAIMessages = append(openAIMessages, userMsg(msg.Content, parts))
+			converted = parts
+		} else if msg.ToolCall != nil {
+			// Tool calls are paired with their results by call ID
+			call := msg.ToolCall
+			openAIMessages = append(openAIMessages,
+				responses.ResponseInputItemParamOfFunctionCall(call.Arguments, call.ID, call.Name))
+		} else if msg.Role == "tool" {
+			openAIMessages = append(openAIMessages,
+				responses.ResponseInputItemParamOfFunctionCallOutput(msg.CallID, msg.Content))
 		} else {
 			// For other messages or user messages without files
 			openAIMsg := responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRole(msg.Role))
@@ -71,9 +99,7 @@ func (c *OpenAIClient) SendRequest(ctx context.Context, request gptx.Request) (g
 	req := NewRequest(request, openAIMessages, c.userID, openAITools)
 
 	// Signal the start of processing
-	if request.Callbacks.OnStart != nil {
-		request.Callbacks.OnStart(request.Config)
-	}
+	request.Emit(events.TurnStarted{Config: request.Config})
 
 	// Start the streaming request
 	stream := c.client.Responses.NewStreaming(ctx, req)
@@ -81,64 +107,91 @@ func (c *OpenAIClient) SendRequest(ctx context.Context, request gptx.Request) (g
 
 	// Stream and process the response
 	var response responses.Response
+	var streamErr error
+	var state streamState
 	for stream.Next() {
 		data := stream.Current()
 
-		// Check if we have a complete response
-		if data.Response.Status == responses.ResponseStatusCompleted {
-			response = data.AsResponseCompleted().Response
+		// Check if the response is done
+		switch data.Response.Status {
+		case responses.ResponseStatusCompleted, responses.ResponseStatusFailed,
+			responses.ResponseStatusIncomplete:
+			response = data.Response
+		}
+		if response.Status != "" {
+			break
+		}
+
+		// Errors sent as stream events end the stream
+		if data.Type == "error" {
+			event := data.AsError()
+			streamErr = responseError(responses.ResponseError{
+				Code: responses.ResponseErrorCode(event.Code), Message: event.Message,
+			})
 			break
 		}
 
 		// Process streaming events
-		c.handleStreamEvent(data, request)
+		c.handleStreamEvent(data, request, &state)
 	}
 
-	// Check for errors in the stream
-	if err := stream.Err(); err != nil {
-		if request.Callbacks.OnError != nil {
-			request.Callbacks.OnError(err)
-		}
+	// Check for errors in the stream or the response
+	err := apiError(stream.Err())
+	switch {
+	case err != nil:
+	case streamErr != nil:
+		err = streamErr
+	case response.Status == responses.ResponseStatusFailed:
+		err = responseError(response.Error)
+	case response.Status == "":
+		// The connection was closed before the response was done
+		err = &gptx.APIError{Kind: gptx.ErrTimeout, Err: errors.New("stream ended early")}
+	}
+	if err != nil {
+		request.Emit(events.Error{Err: err})
 		// Signal completion even on error
-		if request.Callbacks.OnDone != nil {
-			request.Callbacks.OnDone(getUsageJSON(response.Usage))
-		}
+		request.Emit(usage(response.Usage))
+		request.Emit(events.TurnFinished{Reason: events.FinishError})
 		return gptx.Response{}, fmt.Errorf("openai: %w", err)
 	}
 
-	if response.IncompleteDetails.Reason != "" {
-		err := fmt.Errorf("openai: %s", response.IncompleteDetails.Reason)
-		if request.Callbacks.OnError != nil {
-			request.Callbacks.OnError(err)
-		}
-		// Signal completion even on error
-		if request.Callbacks.OnDone != nil {
-			request.Callbacks.OnDone(getUsageJSON(response.Usage))
-		}
+	if reason := response.IncompleteDetails.Reason; reason != "" {
+		err := fmt.Errorf("openai: %s", reason)
+		request.Emit(events.Error{Err: err})
+		request.Emit(usage(response.Usage))
+		request.Emit(events.TurnFinished{Reason: reason})
 		return gptx.Response{}, err
 	}
 
 	// Process the complete response and extract data
-	responseMessages, hasToolCalls := c.extractResponseData(ctx, &response, request)
+	responseMessages, hasToolCalls := c.extractResponseData(&response, request, state)
 
 	// Signal completion with usage information
-	if request.Callbacks.OnDone != nil {
-		request.Callbacks.OnDone(getUsageJSON(response.Usage))
+	reason := events.FinishCompleted
+	if hasToolCalls {
+		reason = events.FinishToolCalls
 	}
+	request.Emit(usage(response.Usage))
+	request.Emit(events.TurnFinished{Reason: reason})
+
+	// Run the tool calls once the request is done
+	responseMessages = runTools(ctx, request, responseMessages)
 
 	// Return the response
 	return gptx.Response{
 		Messages:     responseMessages,
-		Usage:        getUsageJSON(response.Usage),
+		Usage:        usage(response.Usage).String(),
 		HasToolCalls: hasToolCalls,
+		Parts:        converted,
 	}, nil
 }
 
 // extractResponseData processes a completed response and extracts messages and tool call status.
+// Tool calls are run separately, by runTools.
 func (c *OpenAIClient) extractResponseData(
-	ctx context.Context,
 	response *responses.Response,
 	request gptx.Request,
+	state streamState,
 ) ([]gptx.Message, bool) {
 	messages := []gptx.Message{}
 	hasToolCalls := false
@@ -147,84 +200,75 @@ func (c *OpenAIClient) extractResponseData(
 	for _, item := range response.Output {
 		switch item.AsAny().(type) {
 		case responses.ResponseOutputMessage:
-			// Extract text from the message
-			for _, content := range item.AsMessage().ToParam().Content {
-				if content.OfOutputText != nil {
-					// Add assistant message
+			// Extract text from the message; it was already streamed
+			// through the reply callback as deltas
+			for _, content := range item.AsMessage().Content {
+				switch content.Type {
+				case "output_text":
 					messages = append(messages, gptx.Message{
-						Role:    "assistant",
-						Content: content.OfOutputText.Text,
+						Role: "assistant", Content: content.Text,
 					})
-
-					// Send text via callback
-					if request.Callbacks.OnReply != nil {
-						request.Callbacks.OnReply(content.OfOutputText.Text)
-					}
-				}
-				if content.OfRefusal != nil {
-					// Add refusal as assistant message
+				case "refusal":
 					messages = append(messages, gptx.Message{
-						Role:    "assistant",
-						Content: content.OfRefusal.Refusal,
+						Role: "assistant", Content: content.Refusal,
 					})
-
-					// Send refusal via callback
-					if request.Callbacks.OnReply != nil {
-						request.Callbacks.OnReply(content.OfRefusal.Refusal)
-					}
 				}
 			}
 
 		case responses.ResponseFunctionWebSearch:
 			// Signal that a web search is happening
-			request.Callbacks.OnWebSearch()
+			request.Emit(events.WebSearch{})
 			hasToolCalls = true
 
 		case responses.ResponseFunctionToolCall:
 			// Process tool call
 			toolCall := item.AsFunctionCall()
 			hasToolCalls = true
-
-			if request.ToolHandler != nil {
-				// Execute the tool
-				result, err := request.ToolHandler(ctx, toolCall.Name, toolCall.Arguments)
-				if err != nil {
-					// Handle tool execution error
-					errorMsg := fmt.Sprintf("Error executing tool %s: %s", toolCall.Name, err.Error())
-					if request.Callbacks.OnError != nil {
-						request.Callbacks.OnError(err)
-					}
-
-					// Add error as a message
-					messages = append(messages, gptx.Message{
-						Role:    "system",
-						Content: errorMsg,
-					})
-				} else {
-					// Add successful tool result as a message
-					messages = append(messages, gptx.Message{
-						Role:    "tool",
-						Content: result,
-						Name:    toolCall.Name,
-					})
-
-					// Send result via callback
-					if request.Callbacks.OnReply != nil && result != "" {
-						request.Callbacks.OnReply(result)
-					}
-				}
+			call := gptx.ToolCall{
+				ID: toolCall.CallID, Name: toolCall.Name, Arguments: toolCall.Arguments,
 			}
+			messages = append(messages, gptx.Message{Role: "assistant", ToolCall: &call})
 
 		case responses.ResponseReasoningItem:
-			// Handle reasoning output
-			reasoning := item.AsReasoning()
-			if request.Callbacks.OnReasoning != nil {
-				for _, step := range reasoning.Summary {
-					request.Callbacks.OnReasoning(step.Text)
+			// Handle reasoning output, unless it was streamed
+			if state.reasoned {
+				continue
+			}
+			for i, step := range item.AsReasoning().Summary {
+				if i > 0 {
+					request.Emit(events.ReasoningDelta{Text: "\n\n"})
 				}
+				request.Emit(events.ReasoningDelta{Text: step.Text})
 			}
 		}
 	}
 
 	return messages, hasToolCalls
 }
+
+// runTools runs the tool calls of response messages with the request's tool
+// handler, answering each call with its result or its error.
+func runTools(ctx context.Context, request gptx.Request, messages []gptx.Message) []gptx.Message {
+	var answered []gptx.Message
+	for _, msg := range messages {
+		answered = append(answered, msg)
+		if msg.ToolCall == nil {
+			continue
+		}
+
+		call := *msg.ToolCall
+		result := "Error: no tool handler"
+		if request.ToolHandler != nil {
+			output, err := request.ToolHandler(ctx, call)
+			if err != nil {
+				result = fmt.Sprintf("Error executing tool %s: %s", call.Name, err)
+			} else {
+				result = output
+			}
+		}
+		answered = append(answered, gptx.Message{
+			Role: "tool", Content: result, Name: call.Name, CallID: call.ID,
+		})
+	}
+	return answered
+}
@@ -0,0 +1,68 @@
+// Package openai implements the OpenAI Responses API integration.
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/responses"
+)
+
+// streamErrorPrefix starts the message of errors sent as stream events.
+const streamErrorPrefix = "received error while streaming: "
+
+// apiError classifies an error from the API as a gptx.APIError.
+// Cancellations and unrecognized errors are returned unchanged.
+func apiError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// Errors with an HTTP response
+	var sdkErr *openai.Error
+	if errors.As(err, &sdkErr) {
+		apiErr := &gptx.APIError{
+			Kind:       gptx.StatusErrorKind(sdkErr.StatusCode, sdkErr.Code),
+			StatusCode: sdkErr.StatusCode, Code: sdkErr.Code, Err: err,
+		}
+		if sdkErr.Response != nil {
+			apiErr.RetryAfter = gptx.RetryAfter(sdkErr.Response.Header)
+		}
+		return apiErr
+	}
+
+	// Errors sent as events in the middle of a stream
+	if body, ok := strings.CutPrefix(err.Error(), streamErrorPrefix); ok {
+		var event struct {
+			Code string `json:"code"`
+			Type string `json:"type"`
+		}
+		if json.Unmarshal([]byte(body), &event) == nil {
+			code := event.Code
+			if code == "" {
+				code = event.Type
+			}
+			return &gptx.APIError{
+				Kind: gptx.StatusErrorKind(0, code), Code: code, Err: err,
+			}
+		}
+	}
+
+	if kind := gptx.ErrorKindOf(err); kind != gptx.ErrUnknown {
+		return &gptx.APIError{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// responseError classifies the error of a failed response.
+func responseError(failure responses.ResponseError) error {
+	code := string(failure.Code)
+	return &gptx.APIError{
+		Kind: gptx.StatusErrorKind(0, code), Code: code,
+		Err: errors.New(failure.Message),
+	}
+}
@@ -2,51 +2,77 @@
 package openai
 
 import (
-	"encoding/json"
-
+	"github.com/mohdfareed/gptx-cli/internal/events"
 	"github.com/mohdfareed/gptx-cli/pkg/gptx"
 	"github.com/openai/openai-go/responses"
 )
 
-// getUsageJSON returns usage information as a JSON string.
-// Returns "{}" if usage information isn't available.
-
-// handleStreamEvent processes streaming events from the OpenAI API.
-// getUsageJSON returns usage information as a JSON string.
-// Returns "{}" if usage information isn't available.
-func getUsageJSON(usage responses.ResponseUsage) string {
-	// Since we can't directly access the fields easily, just return
-	// the raw JSON if it's available or empty JSON if it's not
-	rawJSON, err := json.MarshalIndent(usage, "", "  ")
-	if err != nil {
-		return ""
+// usage converts the API's usage information to a usage event.
+func usage(usage responses.ResponseUsage) events.Usage {
+	return events.Usage{
+		InputTokens:     int(usage.InputTokens),
+		CachedTokens:    int(usage.InputTokensDetails.CachedTokens),
+		OutputTokens:    int(usage.OutputTokens),
+		ReasoningTokens: int(usage.OutputTokensDetails.ReasoningTokens),
+		TotalTokens:     int(usage.TotalTokens),
 	}
-	return string(rawJSON)
 }
 
+// Stream events that the SDK doesn't model.
+const (
+	reasoningDeltaEvent = "response.reasoning_summary_text.delta"
+	reasoningPartEvent  = "response.reasoning_summary_part.added"
+)
+
+// streamState tracks the items of a response as they stream.
+type streamState struct {
+	reasoned bool                     // The reasoning summary was streamed
+	calls    map[string]gptx.ToolCall // Function calls by item ID
+}
+
+// handleStreamEvent processes streaming events from the OpenAI API.
 func (c *OpenAIClient) handleStreamEvent(
 	event responses.ResponseStreamEventUnion,
 	request gptx.Request,
+	state *streamState,
 ) {
+	// Reasoning summaries stream as paragraphs of deltas
+	switch event.Type {
+	case reasoningPartEvent:
+		if state.reasoned {
+			request.Emit(events.ReasoningDelta{Text: "\n\n"})
+		}
+		return
+	case reasoningDeltaEvent:
+		state.reasoned = true
+		request.Emit(events.ReasoningDelta{Text: event.Delta})
+		return
+	}
+
 	switch variant := event.AsAny().(type) {
 	case responses.ResponseTextDeltaEvent:
 		// Handle incremental text responses
-		if request.Callbacks.OnReply != nil {
-			request.Callbacks.OnReply(variant.Delta)
-		}
+		request.Emit(events.TextDelta{Text: variant.Delta})
 	case responses.ResponseRefusalDeltaEvent:
 		// Handle model refusals
-		if request.Callbacks.OnReply != nil {
-			request.Callbacks.OnReply(variant.Delta)
+		request.Emit(events.TextDelta{Text: variant.Delta})
+	case responses.ResponseOutputItemAddedEvent:
+		// Function calls are named before their arguments stream
+		if variant.Item.Type == "function_call" {
+			if state.calls == nil {
+				state.calls = map[string]gptx.ToolCall{}
+			}
+			state.calls[variant.Item.ID] = gptx.ToolCall{
+				ID: variant.Item.CallID, Name: variant.Item.Name,
+			}
 		}
 	case responses.ResponseFunctionCallArgumentsDeltaEvent:
-		// Function call arguments are streamed incrementally
-		// We don't handle these at the stream level - wait for complete function calls
+		// Function call arguments are streamed as progress; the call runs
+		// once it is complete
+		call := state.calls[variant.ItemID]
+		request.Emit(events.ToolCallDelta{ID: call.ID, Name: call.Name, Args: variant.Delta})
 	case responses.ResponseWebSearchCallSearchingEvent:
 		// Web search status events are used to indicate that a search is happening
 		// We could emit a special message here if needed
 	}
 }
-
-// This space intentionally left blank
-// The functionality from handleCompleteResponse has been moved to extractResponseData in client.go
@@ -0,0 +1,29 @@
+// Package openai implements the OpenAI Responses API integration.
+package openai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+)
+
+// ListModels lists the models available to the API key.
+// Implements the gptx.ModelLister interface.
+func (c *OpenAIClient) ListModels(ctx context.Context) ([]gptx.ModelInfo, error) {
+	var models []gptx.ModelInfo
+	iter := c.client.Models.ListAutoPaging(ctx)
+	for iter.Next() {
+		model := iter.Current()
+		models = append(models, gptx.ModelInfo{
+			ID:      model.ID,
+			OwnedBy: model.OwnedBy,
+			Created: time.Unix(model.Created, 0),
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("openai: list models: %w", err)
+	}
+	return models, nil
+}
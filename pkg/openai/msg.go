@@ -2,6 +2,7 @@
 package openai
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"mime"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mohdfareed/gptx-cli/pkg/gptx"
+	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/packages/param"
 	"github.com/openai/openai-go/responses"
 )
@@ -16,15 +19,45 @@ import (
 // UserMsg creates a message with text and attached files.
 // Handles text and image files appropriately for the API.
 func UserMsg(text string, files []string) (MsgData, error) {
-	var data []FileData
+	msg := gptx.Message{Role: "user", Content: text, Files: files}
+	parts, err := convertParts(msg, readFile)
+	if err != nil {
+		return MsgData{}, err
+	}
+	return userMsg(msg.Content, parts), nil
+}
+
+// parts converts a user message's files and context, uploading large files
+// through the Files API when the client has uploads enabled.
+func (c *OpenAIClient) parts(ctx context.Context, msg gptx.Message) ([]gptx.Part, error) {
+	return convertParts(msg, func(path string) (gptx.Part, error) {
+		return c.attachFile(ctx, path)
+	})
+}
 
-	// Process each file in the file list
-	for _, path := range files {
-		file, err := readFile(path)
+// convertParts converts a message's attachments, using load for each file.
+func convertParts(msg gptx.Message, load func(string) (gptx.Part, error)) ([]gptx.Part, error) {
+	var parts []gptx.Part
+	for _, path := range msg.Files {
+		part, err := load(path)
 		if err != nil {
-			return MsgData{}, fmt.Errorf("readFile: %w", err)
+			return nil, fmt.Errorf("readFile: %w", err)
 		}
-		data = append(data, file)
+		parts = append(parts, part)
+	}
+
+	// Add context attachments as labeled text
+	for _, attachment := range msg.Context {
+		parts = append(parts, contextPart(attachment))
+	}
+	return parts, nil
+}
+
+// userMsg assembles a user message from its converted attachments and text.
+func userMsg(text string, parts []gptx.Part) MsgData {
+	var data []FileData
+	for _, part := range parts {
+		data = append(data, partData(part))
 	}
 
 	// Add the text content if provided
@@ -35,49 +68,125 @@ func UserMsg(text string, files []string) (MsgData, error) {
 	}
 
 	// Create the complete message with role and content
-	msg := MsgData{
+	return MsgData{
 		OfInputMessage: &responses.ResponseInputItemMessageParam{
 			Role: "user", Content: data,
 		},
 	}
-	return msg, nil
 }
 
-// readFile loads a file from disk and converts it to the appropriate FileData format
+// partData converts an attachment part to its API input.
+func partData(part gptx.Part) FileData {
+	switch {
+	case part.Image && part.FileID != "":
+		image := responses.ResponseInputImageParam{
+			Detail: responses.ResponseInputImageDetailAuto,
+			FileID: param.Opt[string]{Value: part.FileID},
+		}
+		return FileData{OfInputImage: &image}
+	case part.Image:
+		// Create file ID without extension
+		fileID := filepath.Base(part.Source)
+		fileID = fileID[:len(fileID)-len(filepath.Ext(fileID))]
+		image := responses.ResponseInputImageParam{
+			FileID:   param.Opt[string]{Value: "file_" + fileID},
+			ImageURL: param.Opt[string]{Value: part.URL},
+		}
+		return FileData{OfInputImage: &image}
+	case part.FileID != "":
+		file := responses.ResponseInputFileParam{FileID: param.Opt[string]{Value: part.FileID}}
+		return FileData{OfInputFile: &file}
+	case part.URL != "":
+		file := responses.ResponseInputFileParam{
+			Filename: param.Opt[string]{Value: filepath.Base(part.Source)},
+			FileData: param.Opt[string]{Value: part.URL},
+		}
+		return FileData{OfInputFile: &file}
+	default:
+		return FileData{OfInputText: &responses.ResponseInputTextParam{Text: part.Text}}
+	}
+}
+
+// attachFile converts a file like readFile, but uploads images and documents
+// larger than the client's threshold and references them by file ID.
+// Text files are always inlined since the model reads them as text.
+func (c *OpenAIClient) attachFile(ctx context.Context, path string) (gptx.Part, error) {
+	info, err := os.Stat(path)
+	if c.uploads == nil || c.threshold <= 0 || err != nil || info.Size() <= c.threshold {
+		return readFile(path)
+	}
+
+	var purpose openai.FilePurpose
+	switch filepath.Ext(path) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		purpose = openai.FilePurposeVision
+	case ".pdf":
+		purpose = openai.FilePurposeUserData
+	default:
+		return readFile(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return gptx.Part{}, fmt.Errorf("loadFile: %w", err)
+	}
+	id, err := c.uploadFile(ctx, path, data, purpose)
+	if err != nil {
+		return gptx.Part{}, err
+	}
+	return gptx.Part{
+		Source: path, Image: purpose == openai.FilePurposeVision, FileID: id,
+	}, nil
+}
+
+// readFile loads a file from disk and converts it to the appropriate part
 // based on its extension. Currently supported formats:
 // - Images (.jpg, .jpeg, .png, .svg): Converted to base64-encoded image data
+// - Documents (.pdf): Converted to base64-encoded file data
 // - Other files: Treated as text files
 //
 // This function abstracts away the details of file handling, allowing the rest of
 // the application to work with files without worrying about format-specific concerns.
-func readFile(path string) (FileData, error) {
+func readFile(path string) (gptx.Part, error) {
 	// Read the entire file into memory
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return FileData{}, fmt.Errorf("loadFile: %w", err)
+		return gptx.Part{}, fmt.Errorf("loadFile: %w", err)
 	}
 
 	// Process based on file extension
 	switch filepath.Ext(path) {
 	case ".jpg", ".jpeg", ".png", ".svg", ".gif", ".webp":
 		return imageFile(data, path) // Handle image files
+	case ".pdf":
+		return documentFile(data, path) // Handle documents
 	default:
 		return dataFile(data, path) // Handle text files
 	}
 }
 
-func dataFile(data []byte, path string) (FileData, error) {
+func dataFile(data []byte, path string) (gptx.Part, error) {
 	format := "# File: %s\n\n```%s\n%s\n```"
 	ext := filepath.Ext(path)
 	text := fmt.Sprintf(format, path, ext, string(data))
-	file := responses.ResponseInputTextParam{Text: text}
-	return FileData{OfInputText: &file}, nil
+	return gptx.Part{Source: path, Text: text}, nil
 }
 
-func imageFile(data []byte, path string) (FileData, error) {
+func contextPart(attachment gptx.Attachment) gptx.Part {
+	format := "# Context: %s\n\n```\n%s\n```"
+	text := fmt.Sprintf(format, attachment.Label, attachment.Content)
+	return gptx.Part{Source: attachment.Label, Text: text}
+}
+
+func documentFile(data []byte, path string) (gptx.Part, error) {
+	b64 := base64.StdEncoding.EncodeToString(data)
+	url := "data:application/pdf;base64," + b64
+	return gptx.Part{Source: path, URL: url}, nil
+}
+
+func imageFile(data []byte, path string) (gptx.Part, error) {
 	// infer MIME from extension; fallback to sniffing bytes
-	ext := filepath.Ext(path)
-	mimeType := mime.TypeByExtension(ext)
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
 	if mimeType == "" {
 		mimeType = http.DetectContentType(data)
 	}
@@ -85,17 +194,5 @@ func imageFile(data []byte, path string) (FileData, error) {
 	// base64‑encode and assemble
 	b64 := base64.StdEncoding.EncodeToString(data)
 	url := fmt.Sprintf("data:%s;base64,%s", mimeType, b64)
-
-	// Create file ID without extension
-	fileID := filepath.Base(path)
-	if ext := filepath.Ext(fileID); ext != "" {
-		fileID = fileID[:len(fileID)-len(ext)]
-	}
-
-	// create image file
-	image := responses.ResponseInputImageParam{
-		FileID:   param.Opt[string]{Value: "file_" + fileID},
-		ImageURL: param.Opt[string]{Value: url},
-	}
-	return FileData{OfInputImage: &image}, nil
+	return gptx.Part{Source: path, Image: true, URL: url}, nil
 }
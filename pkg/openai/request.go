@@ -30,28 +30,33 @@ func NewRequest(
 		ParallelToolCalls: param.Opt[bool]{Value: true},  // Allow parallel tool usage
 	}
 
-	// Apply temperature setting if specified
-	// Temperature controls randomness: higher values make output more random,
-	// lower values make it more deterministic and focused
-	if request.Config.Temp >= 0 {
-		data.Temperature = param.Opt[float64]{Value: request.Config.Temp}
+	// Apply the parameters supported by the model
+	params := gptx.RequestParams(request.Config)
+	if params.Temperature != nil {
+		data.Temperature = param.Opt[float64]{Value: *params.Temperature}
+	}
+	if params.MaxOutputTokens > 0 {
+		data.MaxOutputTokens = param.Opt[int64]{Value: int64(params.MaxOutputTokens)}
 	}
 
-	// Apply token limit if specified
-	// This limits the maximum length of the model's response
-	if request.Config.Tokens >= 0 {
-		data.MaxOutputTokens = param.Opt[int64]{
-			Value: int64(request.Config.Tokens),
+	// Constrain the reply to the schema if specified
+	if request.Schema != nil {
+		data.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{
+				OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+					Name:   request.Schema.Name,
+					Schema: request.Schema.Schema,
+					Strict: param.Opt[bool]{Value: true},
+				},
+			},
 		}
 	}
 
-	// Reasoning setting is currently disabled
-	// When enabled, this would control how much of the model's reasoning
-	// process is exposed in the response
-	if request.Config.Reason {
+	// Control how much the model reasons and what it reports about it
+	if params.Reasoning != "" {
 		data.Reasoning = shared.ReasoningParam{
-			Effort:          shared.ReasoningEffortHigh,
-			GenerateSummary: shared.ReasoningGenerateSummaryDetailed,
+			Effort:          shared.ReasoningEffort(params.Reasoning),
+			GenerateSummary: shared.ReasoningGenerateSummary(params.Summary),
 		}
 	}
 	return data
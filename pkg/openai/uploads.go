@@ -0,0 +1,212 @@
+// Package openai implements OpenAI's Responses API integration.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Upload is a file uploaded through the Files API.
+type Upload struct {
+	ID       string    `json:"id"`       // Provider file ID
+	Name     string    `json:"name"`     // Original file name
+	Size     int64     `json:"size"`     // Size in bytes
+	Uploaded time.Time `json:"uploaded"` // Upload time
+	Used     time.Time `json:"used"`     // Last time the upload was referenced
+}
+
+// UploadCache maps file content hashes to uploaded file IDs.
+// It is persisted as JSON so uploads are reused across runs.
+type UploadCache struct {
+	path    string            // Cache file, empty for in-memory caches
+	Entries map[string]Upload `json:"entries"` // Uploads indexed by content hash
+	mu      sync.Mutex        // Protects concurrent access to entries
+}
+
+// LoadUploadCache loads the upload cache from the given path.
+// A missing file yields an empty cache; an empty path keeps it in memory.
+func LoadUploadCache(path string) (*UploadCache, error) {
+	cache := &UploadCache{path: path, Entries: make(map[string]Upload)}
+	if path == "" {
+		return cache, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("upload cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("upload cache %q: %w", path, err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]Upload)
+	}
+	return cache, nil
+}
+
+// Save writes the cache back to disk.
+func (c *UploadCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("upload cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("upload cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o600); err != nil {
+		return fmt.Errorf("upload cache: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the cached upload for a key and marks it as used.
+func (c *UploadCache) lookup(key string) (Upload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	upload, ok := c.Entries[key]
+	if ok {
+		upload.Used = time.Now()
+		c.Entries[key] = upload
+	}
+	return upload, ok
+}
+
+// store records a new upload under a key.
+func (c *UploadCache) store(key string, upload Upload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[key] = upload
+}
+
+// MARK: Client Integration
+// ============================================================================
+
+// WithUploads enables uploading attachments larger than threshold bytes
+// through the Files API, reusing the IDs recorded in the cache. File IDs
+// belong to an account, so uploads are cached per base URL, empty for
+// the default API, and API key.
+func (c *OpenAIClient) WithUploads(cache *UploadCache, baseURL string, threshold int64) *OpenAIClient {
+	c.uploads = cache
+	c.threshold = threshold
+	c.account = strings.TrimSuffix(baseURL, "/") + "#" + c.keyHash
+	return c
+}
+
+// uploadFile uploads file data once and returns its file ID.
+// Files are keyed by account, content hash and purpose, so renamed or
+// moved files still hit the cache. Cached files the provider no longer
+// has are uploaded again.
+func (c *OpenAIClient) uploadFile(
+	ctx context.Context, path string, data []byte, purpose openai.FilePurpose,
+) (string, error) {
+	sum := sha256.Sum256(data)
+	key := c.account + "|" + string(purpose) + ":" + hex.EncodeToString(sum[:])
+	if upload, ok := c.uploads.lookup(key); ok {
+		_, err := c.client.Files.Get(ctx, upload.ID)
+		if err == nil {
+			return upload.ID, c.uploads.Save()
+		} else if !isNotFound(err) {
+			return "", fmt.Errorf("upload %s: %w", upload.ID, err)
+		}
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	name := filepath.Base(path)
+	file, err := c.client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(bytes.NewReader(data), name, mimeType),
+		Purpose: purpose,
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload %q: %w", path, err)
+	}
+
+	now := time.Now()
+	c.uploads.store(key, Upload{
+		ID: file.ID, Name: name, Size: int64(len(data)),
+		Uploaded: now, Used: now,
+	})
+	return file.ID, c.uploads.Save()
+}
+
+// PruneUploads deletes the account's uploads that were not used within
+// maxAge, or that no longer exist on the provider, and removes them from
+// the cache.
+// A zero maxAge prunes every upload. Returns the number of pruned uploads.
+func (c *OpenAIClient) PruneUploads(ctx context.Context, maxAge time.Duration) (int, error) {
+	if c.uploads == nil {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	pruned := 0
+	for key, upload := range c.Uploads() {
+		if maxAge > 0 && upload.Used.After(cutoff) {
+			// Keep recently used uploads that still exist remotely
+			if _, err := c.client.Files.Get(ctx, upload.ID); !isNotFound(err) {
+				if err != nil {
+					return pruned, fmt.Errorf("upload %s: %w", upload.ID, err)
+				}
+				continue
+			}
+		} else if _, err := c.client.Files.Delete(ctx, upload.ID); err != nil && !isNotFound(err) {
+			return pruned, fmt.Errorf("delete upload %s: %w", upload.ID, err)
+		}
+
+		c.uploads.mu.Lock()
+		delete(c.uploads.Entries, key)
+		c.uploads.mu.Unlock()
+		pruned++
+	}
+	return pruned, c.uploads.Save()
+}
+
+// Uploads returns a snapshot of the client account's cached uploads
+// indexed by content key.
+func (c *OpenAIClient) Uploads() map[string]Upload {
+	uploads := make(map[string]Upload)
+	if c.uploads == nil {
+		return uploads
+	}
+
+	c.uploads.mu.Lock()
+	defer c.uploads.mu.Unlock()
+	for key, upload := range c.uploads.Entries {
+		if strings.HasPrefix(key, c.account+"|") {
+			uploads[key] = upload
+		}
+	}
+	return uploads
+}
+
+// isNotFound reports whether err is a 404 from the API.
+func isNotFound(err error) bool {
+	var apiErr *openai.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
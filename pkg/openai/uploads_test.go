@@ -0,0 +1,125 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+// filesServer is a fake Files API that stores uploads in memory.
+type filesServer struct {
+	files   map[string]string // File names by ID
+	uploads int               // Number of uploads received
+	mu      sync.Mutex
+}
+
+func (s *filesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	id := strings.TrimPrefix(r.URL.Path, "/files/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/files":
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.uploads++
+		id = fmt.Sprintf("file-%d", s.uploads)
+		s.files[id] = header.Filename
+	case s.files[id] == "":
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error": {"message": "No such File object: %s"}}`, id)
+		return
+	case r.Method == http.MethodDelete:
+		delete(s.files, id)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"id": id, "object": "file", "filename": s.files[id], "purpose": "vision",
+		"bytes": 1, "created_at": 0, "status": "processed", "deleted": true,
+	})
+}
+
+// TestUploads uploads a large attachment once per account, reuses it,
+// uploads it again once the provider loses it, and prunes it.
+func TestUploads(t *testing.T) {
+	server := &filesServer{files: make(map[string]string)}
+	api := httptest.NewServer(server)
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 64)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := LoadUploadCache(filepath.Join(t.TempDir(), "uploads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := func(key string) *OpenAIClient {
+		return NewOpenAIClient(key, option.WithBaseURL(api.URL), option.WithMaxRetries(0)).
+			WithUploads(cache, api.URL, 16)
+	}
+	work, home := client("work-key"), client("home-key")
+	ctx := context.Background()
+
+	attach := func(c *OpenAIClient, wantID string, wantUploads int) {
+		t.Helper()
+		part, err := c.attachFile(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.FileID != wantID || !part.Image {
+			t.Errorf("part = %+v, want image %s", part, wantID)
+		}
+		if server.uploads != wantUploads {
+			t.Errorf("%d uploads, want %d", server.uploads, wantUploads)
+		}
+	}
+
+	attach(work, "file-1", 1) // uploaded
+	attach(work, "file-1", 1) // reused
+	attach(home, "file-2", 2) // another account's upload
+	if uploads := work.Uploads(); len(uploads) != 1 {
+		t.Errorf("work uploads = %v, want only its own", uploads)
+	}
+
+	delete(server.files, "file-1") // expired on the provider
+	attach(work, "file-3", 3)
+
+	// Recently used uploads are kept, and pruning all leaves other accounts
+	if pruned, err := work.PruneUploads(ctx, time.Hour); err != nil || pruned != 0 {
+		t.Errorf("PruneUploads(1h) = %d, %v, want 0", pruned, err)
+	}
+	if pruned, err := work.PruneUploads(ctx, 0); err != nil || pruned != 1 {
+		t.Errorf("PruneUploads(0) = %d, %v, want 1", pruned, err)
+	}
+	if _, ok := server.files["file-3"]; ok {
+		t.Error("pruned upload file-3 was not deleted")
+	}
+	if len(work.Uploads()) != 0 || len(home.Uploads()) != 1 {
+		t.Errorf("uploads after prune: work %v, home %v", work.Uploads(), home.Uploads())
+	}
+
+	// The cache persists across runs
+	reloaded, err := LoadUploadCache(cache.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Entries) != 1 {
+		t.Errorf("reloaded %d entries, want 1", len(reloaded.Entries))
+	}
+}